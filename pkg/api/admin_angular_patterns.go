@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (hs *HTTPServer) AdminGetAngularPatterns(c *contextmodel.ReqContext) response.Response {
+	lastUpdated, err := hs.angularDetectorsProvider.LastUpdated(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get angular patterns last update time", err)
+	}
+	patterns, provenance := hs.angularDetectorsProvider.Patterns()
+	provenanceDTO := newAngularPatternsProvenanceDTO(provenance)
+	schemaStatus := hs.angularDetectorsProvider.SchemaStatus()
+
+	result := dtos.AngularPatternsResponse{
+		Patterns:    make([]dtos.AngularPatternDTO, 0, len(patterns)),
+		LastUpdated: lastUpdated,
+		SchemaStatus: dtos.AngularPatternsSchemaStatusDTO{
+			SchemaVersion:          schemaStatus.SchemaVersion,
+			SupportedSchemaVersion: schemaStatus.SupportedSchemaVersion,
+			SkippedPatterns:        schemaStatus.SkippedPatterns,
+			KeptPrevious:           schemaStatus.KeptPrevious,
+		},
+		Stale: hs.angularDetectorsProvider.IsStale(),
+	}
+	for _, p := range patterns {
+		result.Patterns = append(result.Patterns, dtos.AngularPatternDTO{
+			Name:       p.Name,
+			Type:       string(p.Type),
+			Pattern:    p.Pattern,
+			Severity:   string(p.Severity),
+			Provenance: &provenanceDTO,
+		})
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+func (hs *HTTPServer) AdminRefreshAngularPatterns(c *contextmodel.ReqContext) response.Response {
+	if hs.angularDetectorsProvider.IsDisabled() {
+		return response.Error(http.StatusBadRequest, "Dynamic angular detection patterns are disabled", nil)
+	}
+	if err := hs.angularDetectorsProvider.Refresh(c.Req.Context()); err != nil {
+		if errors.Is(err, angulardetectorsprovider.ErrPatternsLocked) {
+			return response.Error(http.StatusConflict, "Angular patterns are being changed by another instance", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to refresh angular patterns", err)
+	}
+	return response.Respond(http.StatusOK, "Angular patterns refreshed successfully")
+}
+
+func (hs *HTTPServer) AdminGetAngularPatternsVersions(c *contextmodel.ReqContext) response.Response {
+	versions, err := hs.angularDetectorsProvider.Versions(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get angular patterns versions", err)
+	}
+	pin, pinned, err := hs.angularDetectorsProvider.Pin(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get pinned angular patterns version", err)
+	}
+
+	result := dtos.AngularPatternsVersionsResponse{
+		Versions: make([]dtos.AngularPatternsVersionDTO, 0, len(versions)),
+	}
+	for _, v := range versions {
+		result.Versions = append(result.Versions, dtos.AngularPatternsVersionDTO{
+			Hash:       v.Hash,
+			CreatedAt:  v.CreatedAt,
+			Patterns:   v.Patterns,
+			Provenance: newAngularPatternsProvenanceDTO(hs.angularDetectorsProvider.VersionProvenance(v)),
+		})
+	}
+	if pinned {
+		result.Pin = &dtos.AngularPatternsPinDTO{Hash: pin.Hash, CreatedAt: pin.CreatedAt}
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+func (hs *HTTPServer) AdminGetAngularPatternsHistory(c *contextmodel.ReqContext) response.Response {
+	history, err := hs.angularDetectorsProvider.History(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get angular patterns history", err)
+	}
+
+	result := dtos.AngularPatternsHistoryResponse{
+		Entries: make([]dtos.AngularPatternsHistoryEntryDTO, 0, len(history)),
+	}
+	for _, e := range history {
+		diff := dtos.AngularPatternsDiffDTO{
+			Added:   e.Diff.Added,
+			Removed: e.Diff.Removed,
+			Changed: make([]dtos.AngularPatternChangeDTO, 0, len(e.Diff.Changed)),
+		}
+		for _, ch := range e.Diff.Changed {
+			diff.Changed = append(diff.Changed, dtos.AngularPatternChangeDTO{Name: ch.Name, Before: ch.Before, After: ch.After})
+		}
+		result.Entries = append(result.Entries, dtos.AngularPatternsHistoryEntryDTO{
+			Version:      e.Version,
+			Hash:         e.Hash,
+			PreviousHash: e.PreviousHash,
+			CreatedAt:    e.CreatedAt,
+			Diff:         diff,
+			Rollback:     e.Rollback,
+		})
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+func (hs *HTTPServer) AdminRollbackAngularPatterns(c *contextmodel.ReqContext) response.Response {
+	cmd := dtos.RollbackAngularPatternsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if cmd.Hash == "" {
+		return response.Error(http.StatusBadRequest, "Missing version hash", nil)
+	}
+	if err := hs.angularDetectorsProvider.Rollback(c.Req.Context(), cmd.Hash); err != nil {
+		if errors.Is(err, angularpatternsstore.ErrVersionNotFound) {
+			return response.Error(http.StatusNotFound, "Angular patterns version not found", err)
+		}
+		if errors.Is(err, angulardetectorsprovider.ErrPatternsLocked) {
+			return response.Error(http.StatusConflict, "Angular patterns are being changed by another instance", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to roll back angular patterns", err)
+	}
+	return response.Respond(http.StatusOK, "Angular patterns rolled back successfully")
+}
+
+func (hs *HTTPServer) AdminReleaseAngularPatternsPin(c *contextmodel.ReqContext) response.Response {
+	if err := hs.angularDetectorsProvider.ReleasePin(c.Req.Context()); err != nil {
+		if errors.Is(err, angulardetectorsprovider.ErrPatternsLocked) {
+			return response.Error(http.StatusConflict, "Angular patterns are being changed by another instance", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to release angular patterns pin", err)
+	}
+	return response.Respond(http.StatusOK, "Angular patterns pin released successfully")
+}
+
+func newAngularPatternsProvenanceDTO(p angulardetectorsprovider.Provenance) dtos.AngularPatternsProvenanceDTO {
+	return dtos.AngularPatternsProvenanceDTO{
+		Source:        p.Source,
+		FetchedAt:     p.FetchedAt,
+		Hash:          p.Hash,
+		SchemaVersion: p.SchemaVersion,
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminAngularPatterns(t *testing.T) {
+	oldPatterns := angulardetectorsprovider.GCOMPatterns{
+		{Name: "PanelCtrl", Pattern: "PanelCtrl", Type: angulardetectorsprovider.GCOMPatternTypeContains},
+	}
+	newPatterns := angulardetectorsprovider.GCOMPatterns{
+		{Name: "PanelCtrl", Pattern: "PanelCtrl", Type: angulardetectorsprovider.GCOMPatternTypeContains},
+		{Name: "QueryCtrl", Pattern: "QueryCtrl", Type: angulardetectorsprovider.GCOMPatternTypeContains},
+	}
+	admin := &user.SignedInUser{OrgID: 1, OrgRole: org.RoleAdmin, IsGrafanaAdmin: true}
+
+	setup := func(t *testing.T) (*webtest.Server, *angulardetectorsprovider.Dynamic) {
+		provider := newAngularDetectorsProvider(t, oldPatterns, newPatterns)
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.log = log.NewNopLogger()
+			hs.angularDetectorsProvider = provider
+		})
+		return server, provider
+	}
+
+	getVersions := func(t *testing.T, server *webtest.Server, u *user.SignedInUser) (dtos.AngularPatternsVersionsResponse, int) {
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/plugins/angular-patterns/versions"), u)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, res.Body.Close()) })
+		var resp dtos.AngularPatternsVersionsResponse
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		}
+		return resp, res.StatusCode
+	}
+
+	rollback := func(t *testing.T, server *webtest.Server, hash string) int {
+		req := webtest.RequestWithSignedInUser(server.NewPostRequest(
+			"/api/admin/plugins/angular-patterns/rollback",
+			strings.NewReader(`{"hash": "`+hash+`"}`),
+		), admin)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	t.Run("requires grafana admin", func(t *testing.T) {
+		server, _ := setup(t)
+		_, code := getVersions(t, server, &user.SignedInUser{OrgID: 1, OrgRole: org.RoleAdmin})
+		require.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("rollback and release pin", func(t *testing.T) {
+		server, _ := setup(t)
+
+		resp, code := getVersions(t, server, admin)
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Versions, 2)
+		require.Nil(t, resp.Pin)
+		for _, v := range resp.Versions {
+			require.Equal(t, v.Hash, v.Provenance.Hash)
+			require.Equal(t, v.CreatedAt, v.Provenance.FetchedAt)
+		}
+
+		oldHash := resp.Versions[1].Hash
+		require.Equal(t, http.StatusOK, rollback(t, server, oldHash))
+		resp, code = getVersions(t, server, admin)
+		require.Equal(t, http.StatusOK, code)
+		require.NotNil(t, resp.Pin)
+		require.Equal(t, oldHash, resp.Pin.Hash)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodDelete, "/api/admin/plugins/angular-patterns/pin", nil), admin)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		resp, code = getVersions(t, server, admin)
+		require.Equal(t, http.StatusOK, code)
+		require.Nil(t, resp.Pin)
+	})
+
+	t.Run("rollback to unknown version", func(t *testing.T) {
+		server, _ := setup(t)
+		require.Equal(t, http.StatusNotFound, rollback(t, server, "does-not-exist"))
+	})
+
+	t.Run("rollback without hash", func(t *testing.T) {
+		server, _ := setup(t)
+		require.Equal(t, http.StatusBadRequest, rollback(t, server, ""))
+	})
+
+	t.Run("get history", func(t *testing.T) {
+		server, _ := setup(t)
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/plugins/angular-patterns/history"), admin)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, res.Body.Close()) })
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var resp dtos.AngularPatternsHistoryResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		require.Len(t, resp.Entries, 2)
+		require.Equal(t, 2, resp.Entries[0].Version)
+		require.Equal(t, resp.Entries[1].Hash, resp.Entries[0].PreviousHash)
+		require.Len(t, resp.Entries[0].Diff.Added, 1)
+		require.Contains(t, string(resp.Entries[0].Diff.Added[0]), "QueryCtrl")
+		require.Empty(t, resp.Entries[0].Diff.Removed)
+		require.Empty(t, resp.Entries[0].Diff.Changed)
+	})
+
+	t.Run("get patterns", func(t *testing.T) {
+		server, _ := setup(t)
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/plugins/angular-patterns"), admin)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, res.Body.Close()) })
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var resp dtos.AngularPatternsResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		require.WithinDuration(t, time.Now(), resp.LastUpdated, time.Minute)
+		require.Equal(t, 1, resp.SchemaStatus.SupportedSchemaVersion)
+		require.Zero(t, resp.SchemaStatus.SkippedPatterns)
+		require.False(t, resp.SchemaStatus.KeptPrevious)
+		require.False(t, resp.Stale)
+		require.Len(t, resp.Patterns, len(newPatterns))
+		for i, p := range newPatterns {
+			require.Equal(t, p.Name, resp.Patterns[i].Name)
+			require.Equal(t, string(p.Type), resp.Patterns[i].Type)
+			require.Equal(t, p.Pattern, resp.Patterns[i].Pattern)
+			require.NotNil(t, resp.Patterns[i].Provenance)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		var gcomCalls int
+		gcom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			gcomCalls++
+			require.NoError(t, json.NewEncoder(w).Encode(oldPatterns))
+		}))
+		t.Cleanup(gcom.Close)
+		provider, err := angulardetectorsprovider.ProvideDynamic(
+			&config.Cfg{GrafanaComURL: gcom.URL},
+			angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+			gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+			nil,
+			featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+			prometheus.NewRegistry(),
+		)
+		require.NoError(t, err)
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.log = log.NewNopLogger()
+			hs.angularDetectorsProvider = provider
+		})
+
+		req := webtest.RequestWithSignedInUser(server.NewPostRequest("/api/admin/plugins/angular-patterns/refresh", nil), admin)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, 1, gcomCalls)
+
+		patterns, _ := provider.Patterns()
+		require.Equal(t, oldPatterns, patterns)
+	})
+}
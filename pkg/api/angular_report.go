@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+)
+
+// GetAngularReport returns the latest Angular detection report for the installed external plugins.
+// The report is generated if it does not exist yet, or if the refresh query parameter is true.
+func (hs *HTTPServer) GetAngularReport(c *contextmodel.ReqContext) response.Response {
+	ctx := c.Req.Context()
+	report, ok, err := hs.angularReportService.Get(ctx)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get angular report", err)
+	}
+	if !ok || c.QueryBool("refresh") {
+		report, err = hs.angularReportService.Generate(ctx)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to generate angular report", err)
+		}
+	}
+
+	result := dtos.AngularReportResponse{
+		GeneratedAt: report.GeneratedAt,
+		Plugins:     make([]dtos.AngularReportPluginDTO, 0, len(report.Results)),
+	}
+	for _, r := range report.Results {
+		result.Plugins = append(result.Plugins, dtos.AngularReportPluginDTO{
+			PluginID:        r.PluginID,
+			AngularDetected: r.AngularDetected,
+			MatchedPatterns: r.MatchedPatterns,
+			Error:           r.Error,
+			InspectedAt:     r.InspectedAt,
+		})
+	}
+	return response.JSON(http.StatusOK, result)
+}
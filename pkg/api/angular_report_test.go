@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularinspector"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularreport"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_GetAngularReport(t *testing.T) {
+	admin := &user.SignedInUser{OrgID: 1, OrgRole: org.RoleAdmin, IsGrafanaAdmin: true}
+
+	setup := func(t *testing.T) (*webtest.Server, registry.Service) {
+		dynamic := newAngularDetectorsProvider(t, nil)
+		file := angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: t.TempDir()})
+		inspector, err := angularinspector.ProvideService(&config.Cfg{Features: featuremgmt.WithFeatures()}, dynamic, file)
+		require.NoError(t, err)
+		reg := registry.NewInMemory()
+		require.NoError(t, reg.Add(context.Background(), &plugins.Plugin{
+			JSONData: plugins.JSONData{ID: "angular-panel"},
+			Class:    plugins.ClassExternal,
+			FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(`PanelCtrl`)}),
+		}))
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.log = log.NewNopLogger()
+			hs.angularReportService = angularreport.ProvideService(kvstore.NewFakeKVStore(), inspector, reg, dynamic, file)
+		})
+		return server, reg
+	}
+
+	getReport := func(t *testing.T, server *webtest.Server, u *user.SignedInUser, url string) (dtos.AngularReportResponse, int) {
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest(url), u)
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, res.Body.Close()) })
+		var resp dtos.AngularReportResponse
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+		}
+		return resp, res.StatusCode
+	}
+
+	t.Run("requires grafana admin", func(t *testing.T) {
+		server, _ := setup(t)
+		_, code := getReport(t, server, &user.SignedInUser{OrgID: 1, OrgRole: org.RoleAdmin}, "/api/plugins/angular-report")
+		require.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("generates the report if it does not exist", func(t *testing.T) {
+		server, _ := setup(t)
+		resp, code := getReport(t, server, admin, "/api/plugins/angular-report")
+		require.Equal(t, http.StatusOK, code)
+		require.False(t, resp.GeneratedAt.IsZero())
+		require.Len(t, resp.Plugins, 1)
+		require.Equal(t, "angular-panel", resp.Plugins[0].PluginID)
+		require.True(t, resp.Plugins[0].AngularDetected)
+		require.Equal(t, []string{"PanelCtrl"}, resp.Plugins[0].MatchedPatterns)
+	})
+
+	t.Run("returns the persisted report unless refresh is requested", func(t *testing.T) {
+		server, reg := setup(t)
+		first, code := getReport(t, server, admin, "/api/plugins/angular-report")
+		require.Equal(t, http.StatusOK, code)
+		require.NoError(t, reg.Add(context.Background(), &plugins.Plugin{
+			JSONData: plugins.JSONData{ID: "react-panel"},
+			Class:    plugins.ClassExternal,
+			FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(`react`)}),
+		}))
+
+		cached, code := getReport(t, server, admin, "/api/plugins/angular-report")
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, first.GeneratedAt.Equal(cached.GeneratedAt))
+		require.Len(t, cached.Plugins, 1)
+
+		refreshed, code := getReport(t, server, admin, "/api/plugins/angular-report?refresh=true")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, refreshed.Plugins, 2)
+		require.Equal(t, "react-panel", refreshed.Plugins[1].PluginID)
+		require.False(t, refreshed.Plugins[1].AngularDetected)
+	})
+}
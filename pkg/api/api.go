@@ -412,6 +412,7 @@ func (hs *HTTPServer) registerRoutes() {
 		apiRoute.Any("/plugins/:pluginId/resources", authorize(ac.EvalPermission(pluginaccesscontrol.ActionAppAccess, pluginIDScope)), hs.CallResource)
 		apiRoute.Any("/plugins/:pluginId/resources/*", authorize(ac.EvalPermission(pluginaccesscontrol.ActionAppAccess, pluginIDScope)), hs.CallResource)
 		apiRoute.Get("/plugins/errors", routing.Wrap(hs.GetPluginErrorsList))
+		apiRoute.Get("/plugins/angular-report", reqGrafanaAdmin, routing.Wrap(hs.GetAngularReport))
 		apiRoute.Any("/plugin-proxy/:pluginId/*", authorize(ac.EvalPermission(pluginaccesscontrol.ActionAppAccess, pluginIDScope)), hs.ProxyPluginRequest)
 		apiRoute.Any("/plugin-proxy/:pluginId", authorize(ac.EvalPermission(pluginaccesscontrol.ActionAppAccess, pluginIDScope)), hs.ProxyPluginRequest)
 
@@ -619,6 +620,13 @@ func (hs *HTTPServer) registerRoutes() {
 		adminRoute.Post("/encryption/migrate-secrets/from-plugin", reqGrafanaAdmin, routing.Wrap(hs.AdminMigrateSecretsFromPlugin))
 		adminRoute.Post("/encryption/delete-secretsmanagerplugin-secrets", reqGrafanaAdmin, routing.Wrap(hs.AdminDeleteAllSecretsManagerPluginSecrets))
 
+		adminRoute.Get("/plugins/angular-patterns", reqGrafanaAdmin, routing.Wrap(hs.AdminGetAngularPatterns))
+		adminRoute.Post("/plugins/angular-patterns/refresh", reqGrafanaAdmin, routing.Wrap(hs.AdminRefreshAngularPatterns))
+		adminRoute.Get("/plugins/angular-patterns/versions", reqGrafanaAdmin, routing.Wrap(hs.AdminGetAngularPatternsVersions))
+		adminRoute.Get("/plugins/angular-patterns/history", reqGrafanaAdmin, routing.Wrap(hs.AdminGetAngularPatternsHistory))
+		adminRoute.Post("/plugins/angular-patterns/rollback", reqGrafanaAdmin, routing.Wrap(hs.AdminRollbackAngularPatterns))
+		adminRoute.Delete("/plugins/angular-patterns/pin", reqGrafanaAdmin, routing.Wrap(hs.AdminReleaseAngularPatternsPin))
+
 		adminRoute.Post("/provisioning/dashboards/reload", authorize(ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersDashboards)), routing.Wrap(hs.AdminProvisioningReloadDashboards))
 		adminRoute.Post("/provisioning/plugins/reload", authorize(ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersPlugins)), routing.Wrap(hs.AdminProvisioningReloadPlugins))
 		adminRoute.Post("/provisioning/datasources/reload", authorize(ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersDatasources)), routing.Wrap(hs.AdminProvisioningReloadDatasources))
@@ -1,6 +1,9 @@
 package dtos
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 )
@@ -66,3 +69,112 @@ func (slice PluginList) Swap(i, j int) {
 type InstallPluginCommand struct {
 	Version string `json:"version"`
 }
+
+// InstallPluginResponse contains the Angular detection result of an installed plugin.
+type InstallPluginResponse struct {
+	AngularDetected bool                `json:"angularDetected"`
+	AngularPatterns []AngularPatternDTO `json:"angularPatterns,omitempty"`
+}
+
+// AngularPatternDTO is a dynamic Angular detection pattern.
+type AngularPatternDTO struct {
+	Name       string                        `json:"name"`
+	Type       string                        `json:"type,omitempty"`
+	Pattern    string                        `json:"pattern,omitempty"`
+	Severity   string                        `json:"severity,omitempty"`
+	Provenance *AngularPatternsProvenanceDTO `json:"provenance,omitempty"`
+}
+
+// AngularPatternsProvenanceDTO contains information about where dynamic Angular detection patterns come from.
+type AngularPatternsProvenanceDTO struct {
+	Source        string    `json:"source"`
+	FetchedAt     time.Time `json:"fetchedAt"`
+	Hash          string    `json:"hash"`
+	SchemaVersion int       `json:"schemaVersion"`
+}
+
+// AngularPatternsResponse contains the active dynamic Angular detection patterns.
+type AngularPatternsResponse struct {
+	Patterns     []AngularPatternDTO            `json:"patterns"`
+	LastUpdated  time.Time                      `json:"lastUpdated"`
+	SchemaStatus AngularPatternsSchemaStatusDTO `json:"schemaStatus"`
+	Stale        bool                           `json:"stale"`
+}
+
+// AngularPatternsSchemaStatusDTO contains information about the schema of the latest dynamic Angular detection
+// patterns fetched from GCOM.
+type AngularPatternsSchemaStatusDTO struct {
+	SchemaVersion          int  `json:"schemaVersion"`
+	SupportedSchemaVersion int  `json:"supportedSchemaVersion"`
+	SkippedPatterns        int  `json:"skippedPatterns"`
+	KeptPrevious           bool `json:"keptPrevious"`
+}
+
+// AngularPatternsVersionsResponse contains the stored versions of the dynamic Angular detection patterns.
+type AngularPatternsVersionsResponse struct {
+	Versions []AngularPatternsVersionDTO `json:"versions"`
+	Pin      *AngularPatternsPinDTO      `json:"pin,omitempty"`
+}
+
+// AngularPatternsVersionDTO is a stored version of the dynamic Angular detection patterns.
+type AngularPatternsVersionDTO struct {
+	Hash       string                       `json:"hash"`
+	CreatedAt  time.Time                    `json:"createdAt"`
+	Patterns   json.RawMessage              `json:"patterns"`
+	Provenance AngularPatternsProvenanceDTO `json:"provenance"`
+}
+
+// AngularPatternsPinDTO is the pinned version of the dynamic Angular detection patterns.
+type AngularPatternsPinDTO struct {
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AngularPatternsHistoryResponse contains the audit history of the dynamic Angular detection patterns.
+type AngularPatternsHistoryResponse struct {
+	Entries []AngularPatternsHistoryEntryDTO `json:"entries"`
+}
+
+// AngularPatternsHistoryEntryDTO is a change of the dynamic Angular detection patterns.
+type AngularPatternsHistoryEntryDTO struct {
+	Version      int                    `json:"version"`
+	Hash         string                 `json:"hash"`
+	PreviousHash string                 `json:"previousHash,omitempty"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	Diff         AngularPatternsDiffDTO `json:"diff"`
+	Rollback     bool                   `json:"rollback,omitempty"`
+}
+
+// AngularPatternsDiffDTO contains the differences between two versions of the dynamic Angular detection patterns.
+type AngularPatternsDiffDTO struct {
+	Added   []json.RawMessage         `json:"added"`
+	Removed []json.RawMessage         `json:"removed"`
+	Changed []AngularPatternChangeDTO `json:"changed"`
+}
+
+// AngularPatternChangeDTO is a dynamic Angular detection pattern that has been modified between two versions.
+type AngularPatternChangeDTO struct {
+	Name   string          `json:"name"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// RollbackAngularPatternsCommand is the request body used to roll back the dynamic Angular detection patterns.
+type RollbackAngularPatternsCommand struct {
+	Hash string `json:"hash"`
+}
+
+// AngularReportResponse contains the Angular detection results for all the installed external plugins.
+type AngularReportResponse struct {
+	GeneratedAt time.Time                `json:"generatedAt"`
+	Plugins     []AngularReportPluginDTO `json:"plugins"`
+}
+
+// AngularReportPluginDTO is the Angular detection result for a single plugin.
+type AngularReportPluginDTO struct {
+	PluginID        string    `json:"pluginId"`
+	AngularDetected bool      `json:"angularDetected"`
+	MatchedPatterns []string  `json:"matchedPatterns,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	InspectedAt     time.Time `json:"inspectedAt"`
+}
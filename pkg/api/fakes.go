@@ -1,6 +1,7 @@
 package api
 
 import (
+	"archive/zip"
 	"context"
 
 	"github.com/grafana/grafana/pkg/plugins"
@@ -10,6 +11,11 @@ type fakePluginInstaller struct {
 	plugins.Installer
 
 	plugins map[string]fakePlugin
+
+	// archive is the archive "downloaded" by Add. It is validated with archiveValidator, if any, before the plugin
+	// is installed.
+	archive          *zip.Reader
+	archiveValidator plugins.ArchiveValidator
 }
 
 type fakePlugin struct {
@@ -21,7 +27,12 @@ func NewFakePluginInstaller() *fakePluginInstaller {
 	return &fakePluginInstaller{plugins: map[string]fakePlugin{}}
 }
 
-func (pm *fakePluginInstaller) Add(_ context.Context, pluginID, version string, _ plugins.CompatOpts) error {
+func (pm *fakePluginInstaller) Add(ctx context.Context, pluginID, version string, _ plugins.CompatOpts) error {
+	if pm.archiveValidator != nil {
+		if err := pm.archiveValidator.ValidateArchive(ctx, pluginID, pm.archive); err != nil {
+			return err
+		}
+	}
 	pm.plugins[pluginID] = fakePlugin{
 		pluginID: pluginID,
 		version:  version,
@@ -2,9 +2,12 @@ package api
 
 import (
 	"context"
+	"net/http"
 	"time"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
@@ -23,3 +26,50 @@ func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
 	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
 	return healthy
 }
+
+// angularPatternsReadyHandler will return ok if the angular detection patterns cache
+// has been populated, either from the database or from grafana.com. If the cache is
+// still empty it will return http status code 503, so it can be used as a readiness probe.
+// It also reports whether the cached patterns are older than the configured max age, and the status of the
+// background refresh (last successful fetch, consecutive failures and circuit breaker state), which does not
+// affect the status code.
+func (hs *HTTPServer) angularPatternsReadyHandler(ctx *web.Context) {
+	notHeadOrGet := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
+	if notHeadOrGet || ctx.Req.URL.Path != "/api/health/angular-patterns" {
+		return
+	}
+
+	ready := hs.angularDetectorsProvider.IsReady()
+	data := simplejson.New()
+	data.Set("ready", ready)
+	if hs.angularDetectorsProvider.IsDisabled() {
+		data.Set("source", "static")
+	} else {
+		status := hs.angularDetectorsProvider.Status()
+		data.Set("source", string(status.CacheSource))
+		data.Set("stale", status.Stale)
+		data.Set("healthy", status.Healthy())
+		if !status.LastSuccess.IsZero() {
+			data.Set("lastSuccess", status.LastSuccess.UTC().Format(time.RFC3339))
+		}
+		data.Set("consecutiveFailures", status.ConsecutiveFailures)
+		data.Set("circuitBreaker", string(status.CircuitBreakerState))
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if ready {
+		ctx.Resp.WriteHeader(http.StatusOK)
+	} else {
+		ctx.Resp.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	dataBytes, err := data.EncodePretty()
+	if err != nil {
+		hs.log.Error("Failed to encode data", "err", err)
+		return
+	}
+
+	if _, err := ctx.Resp.Write(dataBytes); err != nil {
+		hs.log.Error("Failed to write to response", "err", err)
+	}
+}
@@ -1,16 +1,25 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/db/dbtest"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/web"
 )
@@ -147,6 +156,124 @@ func TestHealthAPI_DatabaseHealthCached(t *testing.T) {
 	require.True(t, healthy.(bool))
 }
 
+func TestHealthAPI_AngularPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		provider            func(t *testing.T) *angulardetectorsprovider.Dynamic
+		expectedCode        int
+		expectedBody        string
+		expectedLastSuccess bool
+	}{
+		{
+			name: "not ready if cache is empty",
+			provider: func(t *testing.T) *angulardetectorsprovider.Dynamic {
+				d, err := angulardetectorsprovider.ProvideDynamic(
+					&config.Cfg{},
+					angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+					gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+					nil,
+					featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+					prometheus.NewRegistry(),
+				)
+				require.NoError(t, err)
+				return d
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"ready": false, "source": "", "stale": false, "healthy": true, "consecutiveFailures": 0, "circuitBreaker": "closed"}`,
+		},
+		{
+			name: "ready if cache is restored from database",
+			provider: func(t *testing.T) *angulardetectorsprovider.Dynamic {
+				return newAngularDetectorsProvider(t, nil)
+			},
+			expectedCode:        http.StatusOK,
+			expectedBody:        `{"ready": true, "source": "database", "stale": false, "healthy": true, "consecutiveFailures": 0, "circuitBreaker": "closed"}`,
+			expectedLastSuccess: true,
+		},
+		{
+			name: "stale if cache is older than max age",
+			provider: func(t *testing.T) *angulardetectorsprovider.Dynamic {
+				store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+				require.NoError(t, store.Set(context.Background(), angulardetectorsprovider.GCOMPatterns{
+					{Name: "PanelCtrl", Type: angulardetectorsprovider.GCOMPatternTypeContains, Pattern: "PanelCtrl"},
+				}))
+				d, err := angulardetectorsprovider.ProvideDynamic(
+					&config.Cfg{AngularDetection: setting.AngularDetectionSettings{PatternsMaxAge: time.Nanosecond}},
+					store,
+					gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+					nil,
+					featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+					prometheus.NewRegistry(),
+				)
+				require.NoError(t, err)
+				return d
+			},
+			expectedCode:        http.StatusOK,
+			expectedBody:        `{"ready": true, "source": "database", "stale": true, "healthy": true, "consecutiveFailures": 0, "circuitBreaker": "closed"}`,
+			expectedLastSuccess: true,
+		},
+		{
+			name: "unhealthy if the latest refresh failed",
+			provider: func(t *testing.T) *angulardetectorsprovider.Dynamic {
+				// Not retried, so the test does not wait for the fetch backoff
+				gcom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				}))
+				t.Cleanup(gcom.Close)
+				d, err := angulardetectorsprovider.ProvideDynamic(
+					&config.Cfg{GrafanaComURL: gcom.URL},
+					angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+					gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+					nil,
+					featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+					prometheus.NewRegistry(),
+				)
+				require.NoError(t, err)
+				require.Error(t, d.Refresh(context.Background()))
+				return d
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"ready": false, "source": "", "stale": false, "healthy": false, "consecutiveFailures": 1, "circuitBreaker": "closed"}`,
+		},
+		{
+			name: "ready if dynamic patterns are disabled",
+			provider: func(t *testing.T) *angulardetectorsprovider.Dynamic {
+				d, err := angulardetectorsprovider.ProvideDynamic(
+					&config.Cfg{},
+					angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+					gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+					nil,
+					featuremgmt.WithFeatures(),
+					prometheus.NewRegistry(),
+				)
+				require.NoError(t, err)
+				return d
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"ready": true, "source": "static"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, hs := setupHealthAPITestEnvironment(t)
+			hs.angularDetectorsProvider = tc.provider(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/health/angular-patterns", nil)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedCode, rec.Code)
+			body := simplejson.MustJson(rec.Body.Bytes())
+			// The last success time is not deterministic, so only check its presence
+			_, hasLastSuccess := body.CheckGet("lastSuccess")
+			require.Equal(t, tc.expectedLastSuccess, hasLastSuccess)
+			body.Del("lastSuccess")
+			b, err := body.MarshalJSON()
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expectedBody, string(b))
+		})
+	}
+}
+
 func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*web.Mux, *HTTPServer) {
 	t.Helper()
 
@@ -162,5 +289,6 @@ func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*we
 	}
 
 	m.Get("/api/health", hs.apiHealthHandler)
+	m.Get("/api/health/angular-patterns", hs.angularPatternsReadyHandler)
 	return m, hs
 }
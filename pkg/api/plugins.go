@@ -26,6 +26,7 @@ import (
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginaccesscontrol"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings"
@@ -441,6 +442,12 @@ func (hs *HTTPServer) InstallPlugin(c *contextmodel.ReqContext) response.Respons
 	}
 	pluginID := web.Params(c.Req)[":pluginId"]
 
+	// Make sure the plugin is checked against up-to-date angular detection patterns. This is the only refresh made
+	// during the installation, the installer and the response below use the cached patterns.
+	if err := hs.angularDetectorsProvider.RefreshIfStale(c.Req.Context()); err != nil {
+		hs.log.Warn("Could not refresh angular detection patterns", "pluginId", pluginID, "error", err)
+	}
+
 	compatOpts := plugins.NewCompatOpts(hs.Cfg.BuildVersion, runtime.GOOS, runtime.GOARCH)
 	err := hs.pluginInstaller.Add(c.Req.Context(), pluginID, dto.Version, compatOpts)
 	if err != nil {
@@ -463,6 +470,9 @@ func (hs *HTTPServer) InstallPlugin(c *contextmodel.ReqContext) response.Respons
 		if errors.Is(err, plugins.ErrInstallCorePlugin) {
 			return response.Error(http.StatusForbidden, "Cannot install or change a Core plugin", err)
 		}
+		if errors.Is(err, angulardetectorsprovider.ErrCriticalPatterns) {
+			return response.Error(http.StatusForbidden, "Plugin matches critical angular detection patterns", err)
+		}
 		var archError repo.ErrArcNotFound
 		if errors.As(err, &archError) {
 			return response.Error(http.StatusNotFound, archError.Error(), nil)
@@ -471,7 +481,34 @@ func (hs *HTTPServer) InstallPlugin(c *contextmodel.ReqContext) response.Respons
 		return response.Error(http.StatusInternalServerError, "Failed to install plugin", err)
 	}
 
-	return response.JSON(http.StatusOK, []byte{})
+	return hs.installedPluginAngularCheck(c.Req.Context(), pluginID)
+}
+
+// installedPluginAngularCheck returns the Angular detection result for a plugin that has just been installed.
+// Plugins matching critical angular detection patterns are rejected by the installer before being installed, if
+// AngularDetection.BlockCriticalOnInstall is enabled.
+func (hs *HTTPServer) installedPluginAngularCheck(ctx context.Context, pluginID string) response.Response {
+	p, exists := hs.pluginStore.Plugin(ctx, pluginID)
+	resp := dtos.InstallPluginResponse{AngularDetected: exists && p.AngularDetected}
+	if !resp.AngularDetected {
+		return response.JSON(http.StatusOK, resp)
+	}
+
+	moduleJs, err := hs.pluginFileStore.File(ctx, pluginID, "module.js")
+	if err != nil || moduleJs == nil {
+		hs.log.Warn("Could not read module.js of installed plugin", "pluginId", pluginID, "error", err)
+		return response.JSON(http.StatusOK, resp)
+	}
+	patterns, provenance := hs.angularDetectorsProvider.MatchingPatterns(moduleJs.Content)
+	provenanceDTO := newAngularPatternsProvenanceDTO(provenance)
+	for _, pattern := range patterns {
+		resp.AngularPatterns = append(resp.AngularPatterns, dtos.AngularPatternDTO{
+			Name:       pattern.Name,
+			Severity:   string(pattern.Severity),
+			Provenance: &provenanceDTO,
+		})
+	}
+	return response.JSON(http.StatusOK, resp)
 }
 
 func (hs *HTTPServer) UninstallPlugin(c *contextmodel.ReqContext) response.Response {
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -19,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/plugins"
@@ -30,8 +33,12 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/pluginscdn"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginaccesscontrol"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings"
 	"github.com/grafana/grafana/pkg/services/updatechecker"
@@ -71,6 +78,8 @@ func Test_PluginsInstallAndUninstall(t *testing.T) {
 			hs.orgService = &orgtest.FakeOrgService{ExpectedOrg: &org.Org{}}
 			hs.pluginInstaller = NewFakePluginInstaller()
 			hs.pluginFileStore = &fakes.FakePluginFileStore{}
+			hs.pluginStore = &fakes.FakePluginStore{}
+			hs.angularDetectorsProvider = newAngularDetectorsProvider(t, nil)
 		})
 
 		t.Run(testName("Install", tc), func(t *testing.T) {
@@ -93,6 +102,163 @@ func Test_PluginsInstallAndUninstall(t *testing.T) {
 	}
 }
 
+func Test_PluginsInstallAngularCheck(t *testing.T) {
+	canInstall := []ac.Permission{{Action: pluginaccesscontrol.ActionInstall}}
+	patterns := angulardetectorsprovider.GCOMPatterns{
+		{Name: "PanelCtrl", Pattern: "PanelCtrl", Type: angulardetectorsprovider.GCOMPatternTypeContains},
+		{Name: "LegacySDK", Pattern: "app/plugins/sdk", Type: angulardetectorsprovider.GCOMPatternTypeContains, Severity: angulardetectorsprovider.GCOMPatternSeverityCritical},
+	}
+
+	type testCase struct {
+		name            string
+		moduleJs        string
+		angularDetected bool
+		blockCritical   bool
+		// installedVersion is the version of the plugin that is already installed, if any.
+		installedVersion string
+		expectedCode     int
+		expectedResp     dtos.InstallPluginResponse
+		// expVersion is the version of the plugin installed after the request, if any.
+		expVersion string
+	}
+	for _, tc := range []testCase{
+		{
+			name:         "not angular",
+			moduleJs:     `console.log("react")`,
+			expectedCode: http.StatusOK,
+			expectedResp: dtos.InstallPluginResponse{},
+			expVersion:   "1.0.2",
+		},
+		{
+			name:            "angular",
+			moduleJs:        `PanelCtrl`,
+			angularDetected: true,
+			expectedCode:    http.StatusOK,
+			expectedResp: dtos.InstallPluginResponse{
+				AngularDetected: true,
+				AngularPatterns: []dtos.AngularPatternDTO{{Name: "PanelCtrl"}},
+			},
+			expVersion: "1.0.2",
+		},
+		{
+			name:            "critical angular is installed if blocking is disabled",
+			moduleJs:        `define(["app/plugins/sdk"], function(sdk) {})`,
+			angularDetected: true,
+			expectedCode:    http.StatusOK,
+			expectedResp: dtos.InstallPluginResponse{
+				AngularDetected: true,
+				AngularPatterns: []dtos.AngularPatternDTO{{Name: "LegacySDK", Severity: "critical"}},
+			},
+			expVersion: "1.0.2",
+		},
+		{
+			name:            "critical angular is not installed if blocking is enabled",
+			moduleJs:        `define(["app/plugins/sdk"], function(sdk) {})`,
+			angularDetected: true,
+			blockCritical:   true,
+			expectedCode:    http.StatusForbidden,
+		},
+		{
+			name:             "critical angular update is rejected and the installed version is kept if blocking is enabled",
+			moduleJs:         `define(["app/plugins/sdk"], function(sdk) {})`,
+			angularDetected:  true,
+			blockCritical:    true,
+			installedVersion: "1.0.0",
+			expectedCode:     http.StatusForbidden,
+			expVersion:       "1.0.0",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := newAngularDetectorsProvider(t, patterns)
+			installer := NewFakePluginInstaller()
+			if tc.installedVersion != "" {
+				installer.plugins["test"] = fakePlugin{pluginID: "test", version: tc.installedVersion}
+			}
+			installer.archive = newTestPluginArchive(t, map[string]string{
+				"test/plugin.json": `{"id": "test"}`,
+				"test/module.js":   tc.moduleJs,
+			})
+			installer.archiveValidator = angulardetectorsprovider.ProvideInstallChecker(&config.Cfg{
+				AngularDetection: setting.AngularDetectionSettings{BlockCriticalOnInstall: tc.blockCritical},
+			}, provider)
+			server := SetupAPITestServer(t, func(hs *HTTPServer) {
+				hs.Cfg = &setting.Cfg{
+					RBACEnabled:        true,
+					PluginAdminEnabled: true,
+					AngularDetection:   setting.AngularDetectionSettings{BlockCriticalOnInstall: tc.blockCritical},
+				}
+				hs.log = log.NewNopLogger()
+				hs.orgService = &orgtest.FakeOrgService{ExpectedOrg: &org.Org{}}
+				hs.pluginInstaller = installer
+				hs.pluginFileStore = &fakes.FakePluginFileStore{
+					FileFunc: func(_ context.Context, _, _ string) (*plugins.File, error) {
+						return &plugins.File{Content: []byte(tc.moduleJs)}, nil
+					},
+				}
+				hs.pluginStore = &fakes.FakePluginStore{PluginList: []plugins.PluginDTO{
+					{JSONData: plugins.JSONData{ID: "test"}, AngularDetected: tc.angularDetected},
+				}}
+				hs.angularDetectorsProvider = provider
+			})
+
+			req := webtest.RequestWithSignedInUser(server.NewPostRequest("/api/plugins/test/install", strings.NewReader(`{"version": "1.0.2"}`)), userWithPermissions(1, canInstall))
+			res, err := server.SendJSON(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedCode, res.StatusCode)
+			if tc.expectedCode == http.StatusOK {
+				var resp dtos.InstallPluginResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+				for i := range resp.AngularPatterns {
+					require.NotNil(t, resp.AngularPatterns[i].Provenance)
+					require.NotEmpty(t, resp.AngularPatterns[i].Provenance.Hash)
+					resp.AngularPatterns[i].Provenance = nil
+				}
+				require.Equal(t, tc.expectedResp, resp)
+			}
+			require.NoError(t, res.Body.Close())
+
+			p, installed := installer.plugins["test"]
+			require.Equal(t, tc.expVersion != "", installed)
+			require.Equal(t, tc.expVersion, p.version)
+		})
+	}
+}
+
+// newTestPluginArchive returns a zip archive containing the provided files, keyed by their path.
+func newTestPluginArchive(t *testing.T, files map[string]string) *zip.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return r
+}
+
+// newAngularDetectorsProvider returns a new angulardetectorsprovider.Dynamic with the provided patterns already cached.
+// If more than one set of patterns is provided, they are stored in order, so the last one is the current one.
+func newAngularDetectorsProvider(t *testing.T, patterns ...angulardetectorsprovider.GCOMPatterns) *angulardetectorsprovider.Dynamic {
+	store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+	for _, p := range patterns {
+		require.NoError(t, store.Set(context.Background(), p))
+	}
+	d, err := angulardetectorsprovider.ProvideDynamic(
+		&config.Cfg{},
+		store,
+		gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+		nil,
+		featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+		prometheus.NewRegistry(),
+	)
+	require.NoError(t, err)
+	return d
+}
+
 func Test_GetPluginAssetCDNRedirect(t *testing.T) {
 	const cdnPluginID = "cdn-plugin"
 	const nonCDNPluginID = "non-cdn-plugin"
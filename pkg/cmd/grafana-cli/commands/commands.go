@@ -66,6 +66,15 @@ func initializeRunner(cmd *utils.ContextCommandLine) (server.Runner, error) {
 	return runner, nil
 }
 
+// runInstallCommand runs a command that installs plugins, which are checked against the same angular detection
+// patterns and block policy as the Grafana server.
+func runInstallCommand(command func(commandLine utils.CommandLine, angularChecker angularInstallChecker) error) func(context *cli.Context) error {
+	return func(context *cli.Context) error {
+		cmd := &utils.ContextCommandLine{Context: context}
+		return command(cmd, newAngularInstallChecker(cmd))
+	}
+}
+
 func runPluginCommand(command func(commandLine utils.CommandLine) error) func(context *cli.Context) error {
 	return func(context *cli.Context) error {
 		cmd := &utils.ContextCommandLine{Context: context}
@@ -80,7 +89,7 @@ var pluginCommands = []*cli.Command{
 	{
 		Name:   "install",
 		Usage:  "install <plugin id> <plugin version (optional)>",
-		Action: runPluginCommand(installCommand),
+		Action: runInstallCommand(installCommand),
 	}, {
 		Name:   "list-remote",
 		Usage:  "list remote available plugins",
@@ -93,12 +102,12 @@ var pluginCommands = []*cli.Command{
 		Name:    "update",
 		Usage:   "update <plugin id>",
 		Aliases: []string{"upgrade"},
-		Action:  runPluginCommand(upgradeCommand),
+		Action:  runInstallCommand(upgradeCommand),
 	}, {
 		Name:    "update-all",
 		Aliases: []string{"upgrade-all"},
 		Usage:   "update all your installed plugins",
-		Action:  runPluginCommand(upgradeAllCommand),
+		Action:  runInstallCommand(upgradeAllCommand),
 	}, {
 		Name:   "ls",
 		Usage:  "list installed plugins (excludes core plugins)",
@@ -108,6 +117,21 @@ var pluginCommands = []*cli.Command{
 		Aliases: []string{"remove"},
 		Usage:   "uninstall <plugin id>",
 		Action:  runPluginCommand(removeCommand),
+	}, {
+		Name:   "detect-angular",
+		Usage:  "detect-angular <plugins path>",
+		Action: runDetectAngularCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "patterns-file",
+				Usage: "JSON file containing the angular detection patterns. If not set, the patterns cached by Grafana are used",
+			},
+			&cli.BoolFlag{
+				Name:  "scan-all-js",
+				Usage: "Scan all the JavaScript files of the plugins, rather than only module.js",
+				Value: false,
+			},
+		},
 	},
 }
 
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/services"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var errMissingDetectAngularPath = errors.New("please specify the path of the plugins to inspect")
+
+// angularDetectionResult is the result of the angular detection for a single plugin.
+type angularDetectionResult struct {
+	pluginID        string
+	version         string
+	matchedPatterns []string
+}
+
+// runDetectAngularCommand runs detectAngularCommand. The Grafana database is initialized only if the cached angular
+// detection patterns are needed.
+func runDetectAngularCommand(context *cli.Context) error {
+	cmd := &utils.ContextCommandLine{Context: context}
+	return detectAngularCommand(cmd, func() (angularpatternsstore.Service, error) {
+		runner, err := initializeRunner(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if runner.Cfg.AngularDetection.CacheBackend == setting.AngularPatternsCacheBackendRemoteCache {
+			return nil, errors.New("reading the angular detection patterns from the remote cache is not supported, use the --patterns-file flag")
+		}
+		return angularpatternsstore.ProvideService(kvstore.ProvideService(runner.SQLStore)), nil
+	})
+}
+
+// detectAngularCommand inspects all the plugins under the provided path and reports which ones are using Angular,
+// alongside the patterns that matched.
+// The patterns are read from the file provided via the --patterns-file flag, or from the patterns cached by Grafana
+// (returned by the provided cachedStore function). If there are no cached patterns, the static patterns are used.
+func detectAngularCommand(c utils.CommandLine, cachedStore func() (angularpatternsstore.Service, error)) error {
+	pluginsPath := c.Args().First()
+	if pluginsPath == "" {
+		return errMissingDetectAngularPath
+	}
+	pluginsPathInfo, err := services.IoHelper.Stat(pluginsPath)
+	if err != nil {
+		return err
+	}
+	if !pluginsPathInfo.IsDir() {
+		return errNotDirectory
+	}
+
+	ctx := context.Background()
+	detectors, source, err := loadAngularDetectors(ctx, c.String("patterns-file"), cachedStore)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Using %d angular detection patterns from %s\n\n", len(detectors), source)
+
+	bundles := services.GetLocalPlugins(pluginsPath)
+	if len(bundles) == 0 {
+		logger.Info("no plugins found\n")
+		return nil
+	}
+	var results []angularDetectionResult
+	for _, bundle := range bundles {
+		for _, fp := range append([]*plugins.FoundPlugin{&bundle.Primary}, bundle.Children...) {
+			r, err := detectAngular(ctx, fp, detectors, c.Bool("scan-all-js"))
+			if err != nil {
+				logger.Warnf("Could not inspect plugin %s: %s\n", fp.JSONData.ID, err)
+				continue
+			}
+			results = append(results, r)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].pluginID < results[j].pluginID
+	})
+
+	var angular int
+	for _, r := range results {
+		if len(r.matchedPatterns) == 0 {
+			logger.Infof("%s %s %s: %s\n", r.pluginID, color.YellowString("@"), r.version, color.GreenString("not angular"))
+			continue
+		}
+		angular++
+		logger.Infof("%s %s %s: %s (matched patterns: %s)\n", r.pluginID, color.YellowString("@"), r.version,
+			color.RedString("angular"), strings.Join(r.matchedPatterns, ", "))
+	}
+	logger.Infof("\n%d of %d plugins are using Angular\n", angular, len(results))
+	return nil
+}
+
+// loadAngularDetectors returns the angular detectors read from the provided patterns file, or from the cached
+// patterns if patternsFile is empty, alongside a human-readable description of their source.
+// If there are no cached patterns, the static detectors are returned.
+func loadAngularDetectors(ctx context.Context, patternsFile string, cachedStore func() (angularpatternsstore.Service, error)) ([]angulardetector.NamedDetector, string, error) {
+	patternsLogger := log.New("plugins.angular.detect")
+	if patternsFile != "" {
+		// nolint:gosec
+		// We can ignore the gosec G304 warning since the file is provided by the user running the command
+		b, err := os.ReadFile(patternsFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("read patterns file: %w", err)
+		}
+		detectors, err := angulardetectorsprovider.ParseNamedDetectors(patternsLogger, b)
+		if err != nil {
+			return nil, "", fmt.Errorf("parse patterns file %q: %w", patternsFile, err)
+		}
+		return detectors, patternsFile, nil
+	}
+
+	store, err := cachedStore()
+	if err != nil {
+		return nil, "", err
+	}
+	cached, ok, err := store.Get(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("get cached patterns: %w", err)
+	}
+	if !ok {
+		static := angulardetector.ChainDetectorsProvider{angularinspector.NewDefaultStaticDetectorsProvider()}
+		return static.ProvideNamedDetectors(ctx), "the static patterns (no cached patterns found)", nil
+	}
+	detectors, err := angulardetectorsprovider.ParseNamedDetectors(patternsLogger, []byte(cached))
+	if err != nil {
+		return nil, "", fmt.Errorf("parse cached patterns: %w", err)
+	}
+	return detectors, "the Grafana cache", nil
+}
+
+// detectAngular inspects the provided plugin with the provided detectors and returns the names of all the
+// detectors that matched.
+func detectAngular(ctx context.Context, fp *plugins.FoundPlugin, detectors []angulardetector.NamedDetector, scanAllJS bool) (angularDetectionResult, error) {
+	names := make(map[angulardetector.AngularDetector]string, len(detectors))
+	static := &angulardetector.StaticDetectorsProvider{Detectors: make([]angulardetector.AngularDetector, 0, len(detectors))}
+	for _, d := range detectors {
+		names[d.Detector] = d.Name
+		static.Detectors = append(static.Detectors, d.Detector)
+	}
+	inspector := &angularinspector.PatternsListInspector{DetectorsProvider: static, ScanAllJS: scanAllJS}
+	matching, err := inspector.MatchingDetectors(ctx, &plugins.Plugin{JSONData: fp.JSONData, FS: fp.FS})
+	if err != nil {
+		return angularDetectionResult{}, err
+	}
+	r := angularDetectionResult{pluginID: fp.JSONData.ID, version: fp.JSONData.Info.Version}
+	for _, d := range matching {
+		r.matchedPatterns = append(r.matchedPatterns, names[d])
+	}
+	return r, nil
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+)
+
+func TestDetectAngularCommand(t *testing.T) {
+	const patterns = `[
+		{"name": "PanelCtrl", "type": "contains", "pattern": "PanelCtrl"},
+		{"name": "QueryCtrl", "type": "regex", "pattern": "[\"']QueryCtrl[\"']"}
+	]`
+
+	noCache := func() (angularpatternsstore.Service, error) {
+		return nil, errors.New("cache should not be used")
+	}
+
+	t.Run("missing path", func(t *testing.T) {
+		err := detectAngularCommand(newDetectAngularCliContext(t, nil), noCache)
+		require.ErrorIs(t, err, errMissingDetectAngularPath)
+	})
+
+	t.Run("path is not a directory", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "file.txt")
+		require.NoError(t, os.WriteFile(fn, []byte("abc"), 0600))
+		err := detectAngularCommand(newDetectAngularCliContext(t, nil, fn), noCache)
+		require.ErrorIs(t, err, errNotDirectory)
+	})
+
+	t.Run("inspects plugins with patterns file", func(t *testing.T) {
+		patternsFile := filepath.Join(t.TempDir(), "patterns.json")
+		require.NoError(t, os.WriteFile(patternsFile, []byte(patterns), 0600))
+		pluginsDir := t.TempDir()
+		writeTestPlugin(t, pluginsDir, "angular-panel", "export class PanelCtrl {}")
+
+		err := detectAngularCommand(newDetectAngularCliContext(t, map[string]string{"patterns-file": patternsFile}, pluginsDir), noCache)
+		require.NoError(t, err)
+	})
+
+	t.Run("loadAngularDetectors", func(t *testing.T) {
+		t.Run("from patterns file", func(t *testing.T) {
+			patternsFile := filepath.Join(t.TempDir(), "patterns.json")
+			require.NoError(t, os.WriteFile(patternsFile, []byte(patterns), 0600))
+
+			detectors, source, err := loadAngularDetectors(context.Background(), patternsFile, noCache)
+			require.NoError(t, err)
+			require.Equal(t, patternsFile, source)
+			require.Len(t, detectors, 2)
+			require.Equal(t, "PanelCtrl", detectors[0].Name)
+			require.Equal(t, "QueryCtrl", detectors[1].Name)
+		})
+
+		t.Run("invalid patterns file", func(t *testing.T) {
+			patternsFile := filepath.Join(t.TempDir(), "patterns.json")
+			require.NoError(t, os.WriteFile(patternsFile, []byte("{"), 0600))
+
+			_, _, err := loadAngularDetectors(context.Background(), patternsFile, noCache)
+			require.Error(t, err)
+		})
+
+		t.Run("from cache", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), []map[string]string{
+				{"name": "ConfigCtrl", "type": "contains", "pattern": "ConfigCtrl"},
+			}))
+
+			detectors, source, err := loadAngularDetectors(context.Background(), "", func() (angularpatternsstore.Service, error) {
+				return store, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "the Grafana cache", source)
+			require.Len(t, detectors, 1)
+			require.Equal(t, "ConfigCtrl", detectors[0].Name)
+		})
+
+		t.Run("falls back to static patterns if cache is empty", func(t *testing.T) {
+			detectors, _, err := loadAngularDetectors(context.Background(), "", func() (angularpatternsstore.Service, error) {
+				return angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()), nil
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, detectors)
+		})
+	})
+
+	t.Run("detectAngular", func(t *testing.T) {
+		patternsFile := filepath.Join(t.TempDir(), "patterns.json")
+		require.NoError(t, os.WriteFile(patternsFile, []byte(patterns), 0600))
+		detectors, _, err := loadAngularDetectors(context.Background(), patternsFile, noCache)
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			name       string
+			moduleJs   string
+			expMatched []string
+		}{
+			{name: "angular", moduleJs: `class PanelCtrl {}; register("QueryCtrl")`, expMatched: []string{"PanelCtrl", "QueryCtrl"}},
+			{name: "not angular", moduleJs: "export const plugin = new PanelPlugin()"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				dir := writeTestPlugin(t, t.TempDir(), "test-panel", tc.moduleJs)
+				r, err := detectAngular(context.Background(), &plugins.FoundPlugin{
+					JSONData: plugins.JSONData{ID: "test-panel", Info: plugins.Info{Version: "1.0.0"}},
+					FS:       plugins.NewLocalFS(dir),
+				}, detectors, false)
+				require.NoError(t, err)
+				require.Equal(t, "test-panel", r.pluginID)
+				require.Equal(t, "1.0.0", r.version)
+				require.Equal(t, tc.expMatched, r.matchedPatterns)
+			})
+		}
+	})
+}
+
+// newDetectAngularCliContext returns a CommandLine with the provided flags and arguments.
+func newDetectAngularCliContext(t *testing.T, flags map[string]string, args ...string) utils.CommandLine {
+	flagSet := flag.NewFlagSet("Test", 0)
+	flagSet.Bool("scan-all-js", false, "")
+	for name, value := range flags {
+		flagSet.String(name, value, "")
+	}
+	require.NoError(t, flagSet.Parse(args))
+	return &utils.ContextCommandLine{Context: cli.NewContext(&cli.App{Name: "Test"}, flagSet, nil)}
+}
+
+// writeTestPlugin writes a panel plugin with the provided id and module.js content in a new sub-directory of the
+// provided directory, and returns the plugin directory.
+func writeTestPlugin(t *testing.T, dir, id, moduleJs string) string {
+	pluginDir := filepath.Join(dir, id)
+	require.NoError(t, os.MkdirAll(pluginDir, 0750))
+	pluginJSON := `{"type": "panel", "name": "Test", "id": "` + id + `", "info": {"version": "1.0.0"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "plugin.json"), []byte(pluginJSON), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "module.js"), []byte(moduleJs), 0600))
+	return pluginDir
+}
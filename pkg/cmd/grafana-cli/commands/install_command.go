@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"archive/zip"
 	"context"
 	"errors"
 	"fmt"
@@ -15,9 +16,17 @@ import (
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/models"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/services"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
 	"github.com/grafana/grafana/pkg/plugins/repo"
 	"github.com/grafana/grafana/pkg/plugins/storage"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/config"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 const installArgsSize = 2
@@ -68,14 +77,14 @@ func logRestartNotice() {
 	logger.Info(color.GreenString("Please restart Grafana after installing or removing plugins. Refer to Grafana documentation for instructions if necessary.\n\n"))
 }
 
-func installCommand(c utils.CommandLine) error {
+func installCommand(c utils.CommandLine, angularChecker angularInstallChecker) error {
 	if err := validateInput(c); err != nil {
 		return err
 	}
 
 	pluginID := c.Args().First()
 	version := c.Args().Get(1)
-	err := installPlugin(context.Background(), pluginID, version, c)
+	err := installPlugin(context.Background(), pluginID, version, c, angularChecker, false)
 	if err == nil {
 		logRestartNotice()
 	}
@@ -84,7 +93,10 @@ func installCommand(c utils.CommandLine) error {
 
 // installPlugin downloads the plugin code as a zip file from the Grafana.com API
 // and then extracts the zip into the plugin's directory.
-func installPlugin(ctx context.Context, pluginID, version string, c utils.CommandLine) error {
+// The archive is checked against the angular detection patterns before being extracted. If replace is true, the
+// installed version of the plugin is only removed once the new archive has been downloaded and checked, so a failed
+// or rejected update keeps the installed version.
+func installPlugin(ctx context.Context, pluginID, version string, c utils.CommandLine, angularChecker angularInstallChecker, replace bool) error {
 	// If a version is specified, check if it is already installed
 	if version != "" {
 		if services.PluginVersionInstalled(pluginID, version, c.PluginDirectory()) {
@@ -114,6 +126,17 @@ func installPlugin(ctx context.Context, pluginID, version string, c utils.Comman
 		}
 	}
 
+	angularResult, err := checkAngular(ctx, angularChecker, pluginID, &archive.File.Reader)
+	if err != nil {
+		return err
+	}
+
+	if replace {
+		if err = uninstallPlugin(ctx, pluginID, c); err != nil {
+			return fmt.Errorf("failed to remove plugin '%s': %w", pluginID, err)
+		}
+	}
+
 	pluginFs := storage.FileSystem(services.Logger, c.PluginDirectory())
 	extractedArchive, err := pluginFs.Extract(ctx, pluginID, storage.SimpleDirNameGeneratorFunc, archive.File)
 	if err != nil {
@@ -132,9 +155,125 @@ func installPlugin(ctx context.Context, pluginID, version string, c utils.Comman
 			return err
 		}
 	}
+
+	warnAngular(ctx, pluginID, extractedArchive.Path, angularResult)
 	return nil
 }
 
+// angularInstallChecker checks the plugin archives against the angular detection patterns before they are extracted.
+// It is implemented by angulardetectorsprovider.InstallChecker.
+type angularInstallChecker interface {
+	plugins.ArchiveValidator
+
+	// MatchingPatterns returns the angular detection patterns matching the plugin in the provided archive.
+	MatchingPatterns(ctx context.Context, pluginID string, archive *zip.Reader) (angulardetectorsprovider.GCOMPatterns, angulardetectorsprovider.Provenance, error)
+}
+
+// newAngularInstallChecker returns an angularInstallChecker that uses the angular detection patterns cached by the
+// Grafana server, without refreshing them from GCOM, and the same block policy.
+// It returns nil if blocking the plugins matching critical patterns is not enabled, so Grafana is only initialized
+// when needed, or if Grafana cannot be initialized or the patterns are cached in the remote cache. In that case, the
+// installed plugins are only inspected with the static patterns.
+func newAngularInstallChecker(cmd *utils.ContextCommandLine) angularInstallChecker {
+	checker, err := func() (angularInstallChecker, error) {
+		cfg, err := setting.NewCfgFromArgs(setting.CommandLineArgs{
+			Config:   cmd.ConfigFile(),
+			HomePath: cmd.HomePath(),
+			Args:     append(strings.Split(cmd.String("configOverrides"), " "), cmd.Args().Slice()...),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("load configuration: %w", err)
+		}
+		if !cfg.AngularDetection.BlockCriticalOnInstall {
+			return nil, nil
+		}
+		runner, err := initializeRunner(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if runner.Cfg.AngularDetection.CacheBackend == setting.AngularPatternsCacheBackendRemoteCache {
+			return nil, errors.New("reading the angular detection patterns from the remote cache is not supported")
+		}
+		features, ok := runner.Features.(*featuremgmt.FeatureManager)
+		if !ok {
+			return nil, errors.New("unexpected feature toggles implementation")
+		}
+		pCfg, err := config.ProvideConfig(runner.SettingsProvider, runner.Cfg, features)
+		if err != nil {
+			return nil, fmt.Errorf("plugins config: %w", err)
+		}
+		gcomClient, err := gcomclient.ProvideClient(pCfg, nil)
+		if err != nil {
+			return nil, fmt.Errorf("gcom client: %w", err)
+		}
+		store := angularpatternsstore.ProvideService(kvstore.ProvideService(runner.SQLStore))
+		dynamic, err := angulardetectorsprovider.ProvideDynamic(pCfg, store, gcomClient, nil, runner.Features, nil)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic angular detectors provider: %w", err)
+		}
+		return angulardetectorsprovider.ProvideInstallChecker(pCfg, dynamic), nil
+	}()
+	if err != nil {
+		services.Logger.Warnf("Could not load the angular detection patterns cached by Grafana, using the static patterns: %s\n", err)
+		return nil
+	}
+	return checker
+}
+
+// angularCheckResult is the result of checkAngular.
+type angularCheckResult struct {
+	// dynamic is true if the plugin has been checked against the dynamic angular detection patterns.
+	dynamic bool
+
+	// patterns contains the names of the dynamic angular detection patterns matching the plugin.
+	patterns []string
+}
+
+// checkAngular checks the provided plugin archive against the dynamic angular detection patterns, before it is
+// extracted. It returns an error if the plugin must not be installed, according to the same block policy as the
+// Grafana server.
+func checkAngular(ctx context.Context, angularChecker angularInstallChecker, pluginID string, archive *zip.Reader) (angularCheckResult, error) {
+	if angularChecker == nil {
+		return angularCheckResult{}, nil
+	}
+	if err := angularChecker.ValidateArchive(ctx, pluginID, archive); err != nil {
+		return angularCheckResult{}, err
+	}
+	patterns, provenance, err := angularChecker.MatchingPatterns(ctx, pluginID, archive)
+	if err != nil {
+		services.Logger.Warnf("Could not check if plugin %s is using Angular: %s", pluginID, err)
+		return angularCheckResult{}, nil
+	}
+	// An empty hash means that there are no cached dynamic patterns
+	r := angularCheckResult{dynamic: provenance.Hash != ""}
+	for _, p := range patterns {
+		r.patterns = append(r.patterns, p.Name)
+	}
+	return r, nil
+}
+
+// warnAngular warns the user if the installed plugin is using Angular. If the plugin has not been checked against the
+// dynamic angular detection patterns, it is inspected with the static patterns.
+func warnAngular(ctx context.Context, pluginID, pluginPath string, r angularCheckResult) {
+	isAngular := len(r.patterns) > 0
+	if !r.dynamic {
+		var err error
+		isAngular, err = angularinspector.NewStaticInspector().Inspect(ctx, &plugins.Plugin{FS: plugins.NewLocalFS(pluginPath)})
+		if err != nil {
+			services.Logger.Warnf("Could not check if plugin %s is using Angular: %s", pluginID, err)
+			return
+		}
+	}
+	if !isAngular {
+		return
+	}
+	if len(r.patterns) > 0 {
+		logger.Info(color.YellowString("Plugin %s is using Angular (matched patterns: %s), which is deprecated and may be disabled in future Grafana versions.\n\n", pluginID, strings.Join(r.patterns, ", ")))
+		return
+	}
+	logger.Info(color.YellowString("Plugin %s is using Angular, which is deprecated and may be disabled in future Grafana versions.\n\n", pluginID))
+}
+
 // uninstallPlugin removes the plugin directory
 func uninstallPlugin(_ context.Context, pluginID string, c utils.CommandLine) error {
 	for _, bundle := range services.GetLocalPlugins(c.PluginDirectory()) {
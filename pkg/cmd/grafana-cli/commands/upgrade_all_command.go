@@ -30,7 +30,7 @@ func shouldUpgrade(installed plugins.FoundPlugin, remote models.Plugin) bool {
 	return installedVersion.LessThan(latestVersion)
 }
 
-func upgradeAllCommand(c utils.CommandLine) error {
+func upgradeAllCommand(c utils.CommandLine, angularChecker angularInstallChecker) error {
 	pluginsDir := c.PluginDirectory()
 
 	localPlugins := services.GetLocalPlugins(pluginsDir)
@@ -58,12 +58,7 @@ func upgradeAllCommand(c utils.CommandLine) error {
 	for _, p := range pluginsToUpgrade {
 		logger.Infof("Updating %v \n", p.JSONData.ID)
 
-		err = uninstallPlugin(ctx, p.JSONData.ID, c)
-		if err != nil {
-			return err
-		}
-
-		err = installPlugin(ctx, p.JSONData.ID, "", c)
+		err = installPlugin(ctx, p.JSONData.ID, "", c, angularChecker, true)
 		if err != nil {
 			return err
 		}
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/fatih/color"
 
@@ -11,7 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
 )
 
-func upgradeCommand(c utils.CommandLine) error {
+func upgradeCommand(c utils.CommandLine, angularChecker angularInstallChecker) error {
 	ctx := context.Background()
 	pluginsDir := c.PluginDirectory()
 	pluginID := c.Args().First()
@@ -27,11 +26,7 @@ func upgradeCommand(c utils.CommandLine) error {
 	}
 
 	if shouldUpgrade(localPlugin, plugin) {
-		if err = uninstallPlugin(ctx, pluginID, c); err != nil {
-			return fmt.Errorf("failed to remove plugin '%s': %w", pluginID, err)
-		}
-
-		err = installPlugin(ctx, pluginID, "", c)
+		err = installPlugin(ctx, pluginID, "", c, angularChecker, true)
 		if err == nil {
 			logRestartNotice()
 		}
@@ -44,12 +44,16 @@ type Cfg struct {
 	Features plugins.FeatureToggles
 
 	AngularSupportEnabled bool
+
+	AngularDetection setting.AngularDetectionSettings
+
+	GCOMClient setting.GCOMClientSettings
 }
 
 func NewCfg(devMode bool, pluginsPath string, pluginSettings setting.PluginSettings, pluginsAllowUnsigned []string,
 	awsAllowedAuthProviders []string, awsAssumeRoleEnabled bool, awsExternalId string, azure *azsettings.AzureSettings, secureSocksDSProxy setting.SecureSocksDSProxySettings,
 	grafanaVersion string, logDatasourceRequests bool, pluginsCDNURLTemplate string, appURL string, tracing Tracing, features plugins.FeatureToggles, angularSupportEnabled bool,
-	grafanaComURL string) *Cfg {
+	grafanaComURL string, angularDetection setting.AngularDetectionSettings, gcomClient setting.GCOMClientSettings) *Cfg {
 	return &Cfg{
 		log:                     log.New("plugin.cfg"),
 		PluginsPath:             pluginsPath,
@@ -69,5 +73,7 @@ func NewCfg(devMode bool, pluginsPath string, pluginSettings setting.PluginSetti
 		GrafanaAppURL:           appURL,
 		Features:                features,
 		AngularSupportEnabled:   angularSupportEnabled,
+		AngularDetection:        angularDetection,
+		GCOMClient:              gcomClient,
 	}
 }
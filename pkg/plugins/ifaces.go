@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"archive/zip"
 	"context"
 	"io/fs"
 	"time"
@@ -25,6 +26,12 @@ type Installer interface {
 	Remove(ctx context.Context, pluginID string) error
 }
 
+// ArchiveValidator validates the archives downloaded from the plugin repository, before they are installed.
+type ArchiveValidator interface {
+	// ValidateArchive returns an error if the plugin with the provided ID must not be installed from the archive.
+	ValidateArchive(ctx context.Context, pluginID string, archive *zip.Reader) error
+}
+
 type PluginSource interface {
 	PluginClass(ctx context.Context) Class
 	PluginURIs(ctx context.Context) []string
@@ -247,6 +247,17 @@ func (s *FakePluginStorage) Extract(ctx context.Context, pluginID string, dirNam
 	return &storage.ExtractedPluginArchive{}, nil
 }
 
+type FakeArchiveValidator struct {
+	ValidateArchiveFunc func(_ context.Context, pluginID string, archive *zip.Reader) error
+}
+
+func (v *FakeArchiveValidator) ValidateArchive(ctx context.Context, pluginID string, archive *zip.Reader) error {
+	if v.ValidateArchiveFunc != nil {
+		return v.ValidateArchiveFunc(ctx, pluginID, archive)
+	}
+	return nil
+}
+
 type FakeProcessManager struct {
 	StartFunc func(_ context.Context, p *plugins.Plugin) error
 	StopFunc  func(_ context.Context, p *plugins.Plugin) error
@@ -23,23 +23,29 @@ type PluginInstaller struct {
 	pluginStorageDirFunc storage.DirNameGeneratorFunc
 	pluginRegistry       registry.Service
 	pluginLoader         loader.Service
+	archiveValidator     plugins.ArchiveValidator
 	log                  log.Logger
 }
 
 func ProvideInstaller(cfg *config.Cfg, pluginRegistry registry.Service, pluginLoader loader.Service,
-	pluginRepo repo.Service) *PluginInstaller {
+	pluginRepo repo.Service, archiveValidator plugins.ArchiveValidator) *PluginInstaller {
 	return New(pluginRegistry, pluginLoader, pluginRepo,
-		storage.FileSystem(log.NewPrettyLogger("installer.fs"), cfg.PluginsPath), storage.SimpleDirNameGeneratorFunc)
+		storage.FileSystem(log.NewPrettyLogger("installer.fs"), cfg.PluginsPath), storage.SimpleDirNameGeneratorFunc,
+		archiveValidator)
 }
 
+// New returns a new PluginInstaller. archiveValidator is optional: if it's nil, the downloaded archives are not
+// validated.
 func New(pluginRegistry registry.Service, pluginLoader loader.Service, pluginRepo repo.Service,
-	pluginStorage storage.ZipExtractor, pluginStorageDirFunc storage.DirNameGeneratorFunc) *PluginInstaller {
+	pluginStorage storage.ZipExtractor, pluginStorageDirFunc storage.DirNameGeneratorFunc,
+	archiveValidator plugins.ArchiveValidator) *PluginInstaller {
 	return &PluginInstaller{
 		pluginLoader:         pluginLoader,
 		pluginRegistry:       pluginRegistry,
 		pluginRepo:           pluginRepo,
 		pluginStorage:        pluginStorage,
 		pluginStorageDirFunc: pluginStorageDirFunc,
+		archiveValidator:     archiveValidator,
 		log:                  log.New("plugin.installer"),
 	}
 }
@@ -51,7 +57,8 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 	}
 
 	var pluginArchive *repo.PluginArchive
-	if plugin, exists := m.plugin(ctx, pluginID); exists {
+	plugin, exists := m.plugin(ctx, pluginID)
+	if exists {
 		if plugin.IsCorePlugin() || plugin.IsBundledPlugin() {
 			return plugins.ErrInstallCorePlugin
 		}
@@ -79,12 +86,6 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 			return fmt.Errorf("could not determine update options for %s", pluginID)
 		}
 
-		// remove existing installation of plugin
-		err = m.Remove(ctx, plugin.ID)
-		if err != nil {
-			return err
-		}
-
 		if pluginArchiveInfo.URL != "" {
 			pluginArchive, err = m.pluginRepo.GetPluginArchiveByURL(ctx, pluginArchiveInfo.URL, compatOpts)
 			if err != nil {
@@ -104,6 +105,19 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 		}
 	}
 
+	// Validate the archive before touching the existing installation, so a rejected update keeps the installed version
+	if err = m.validateArchive(ctx, pluginID, pluginArchive); err != nil {
+		return err
+	}
+
+	if exists {
+		// remove existing installation of plugin
+		err = m.Remove(ctx, plugin.ID)
+		if err != nil {
+			return err
+		}
+	}
+
 	extractedArchive, err := m.pluginStorage.Extract(ctx, pluginID, m.pluginStorageDirFunc, pluginArchive.File)
 	if err != nil {
 		return err
@@ -117,6 +131,9 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 		if err != nil {
 			return fmt.Errorf("%v: %w", fmt.Sprintf("failed to download plugin %s from repository", dep.ID), err)
 		}
+		if err = m.validateArchive(ctx, dep.ID, d); err != nil {
+			return err
+		}
 
 		depArchive, err := m.pluginStorage.Extract(ctx, dep.ID, m.pluginStorageDirFunc, d.File)
 		if err != nil {
@@ -135,6 +152,17 @@ func (m *PluginInstaller) Add(ctx context.Context, pluginID, version string, opt
 	return nil
 }
 
+// validateArchive validates the provided plugin archive with the archive validator, if any.
+func (m *PluginInstaller) validateArchive(ctx context.Context, pluginID string, archive *repo.PluginArchive) error {
+	if m.archiveValidator == nil || archive == nil || archive.File == nil {
+		return nil
+	}
+	if err := m.archiveValidator.ValidateArchive(ctx, pluginID, &archive.File.Reader); err != nil {
+		return fmt.Errorf("validate %s archive: %w", pluginID, err)
+	}
+	return nil
+}
+
 func (m *PluginInstaller) Remove(ctx context.Context, pluginID string) error {
 	plugin, exists := m.plugin(ctx, pluginID)
 	if !exists {
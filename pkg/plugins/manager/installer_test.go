@@ -3,6 +3,7 @@ package manager
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"testing"
@@ -65,7 +66,7 @@ func TestPluginManager_Add_Remove(t *testing.T) {
 			},
 		}
 
-		inst := New(fakes.NewFakePluginRegistry(), loader, pluginRepo, fs, storage.SimpleDirNameGeneratorFunc)
+		inst := New(fakes.NewFakePluginRegistry(), loader, pluginRepo, fs, storage.SimpleDirNameGeneratorFunc, nil)
 		err := inst.Add(context.Background(), pluginID, v1, testCompatOpts())
 		require.NoError(t, err)
 
@@ -152,6 +153,60 @@ func TestPluginManager_Add_Remove(t *testing.T) {
 		})
 	})
 
+	t.Run("Rejected update keeps the installed version", func(t *testing.T) {
+		pluginV1 := createPlugin(t, testPluginID, plugins.ClassExternal, true, true, func(plugin *plugins.Plugin) {
+			plugin.Info.Version = "1.0.0"
+		})
+		reg := &fakes.FakePluginRegistry{
+			Store: map[string]*plugins.Plugin{
+				testPluginID: pluginV1,
+			},
+		}
+		mockZipV2 := &zip.ReadCloser{Reader: zip.Reader{File: []*zip.File{{
+			FileHeader: zip.FileHeader{Name: "test-plugin-2.0.0.zip"},
+		}}}}
+		pluginRepo := &fakes.FakePluginRepo{
+			GetPluginArchiveInfoFunc: func(_ context.Context, _, _ string, _ repo.CompatOpts) (*repo.PluginArchiveInfo, error) {
+				return &repo.PluginArchiveInfo{Version: "2.0.0"}, nil
+			},
+			GetPluginArchiveFunc: func(_ context.Context, _, _ string, _ repo.CompatOpts) (*repo.PluginArchive, error) {
+				return &repo.PluginArchive{File: mockZipV2}, nil
+			},
+		}
+		loader := &fakes.FakeLoader{
+			LoadFunc: func(_ context.Context, _ plugins.PluginSource) ([]*plugins.Plugin, error) {
+				require.Fail(t, "rejected plugin should not be loaded")
+				return nil, nil
+			},
+			UnloadFunc: func(_ context.Context, _ *plugins.Plugin) (*plugins.Plugin, error) {
+				require.Fail(t, "installed plugin should not be unloaded")
+				return nil, nil
+			},
+		}
+		fs := &fakes.FakePluginStorage{
+			ExtractFunc: func(_ context.Context, _ string, _ storage.DirNameGeneratorFunc, _ *zip.ReadCloser) (*storage.ExtractedPluginArchive, error) {
+				require.Fail(t, "rejected archive should not be extracted")
+				return nil, nil
+			},
+		}
+		errRejected := errors.New("rejected")
+		validator := &fakes.FakeArchiveValidator{
+			ValidateArchiveFunc: func(_ context.Context, pluginID string, archive *zip.Reader) error {
+				require.Equal(t, testPluginID, pluginID)
+				require.Equal(t, &mockZipV2.Reader, archive)
+				return errRejected
+			},
+		}
+
+		inst := New(reg, loader, pluginRepo, fs, storage.SimpleDirNameGeneratorFunc, validator)
+		err := inst.Add(context.Background(), testPluginID, "2.0.0", testCompatOpts())
+		require.ErrorIs(t, err, errRejected)
+
+		p, exists := reg.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		require.Equal(t, "1.0.0", p.Info.Version)
+	})
+
 	t.Run("Can't update core or bundled plugin", func(t *testing.T) {
 		tcs := []struct {
 			class plugins.Class
@@ -171,7 +226,7 @@ func TestPluginManager_Add_Remove(t *testing.T) {
 				},
 			}
 
-			pm := New(reg, &fakes.FakeLoader{}, &fakes.FakePluginRepo{}, &fakes.FakePluginStorage{}, storage.SimpleDirNameGeneratorFunc)
+			pm := New(reg, &fakes.FakeLoader{}, &fakes.FakePluginRepo{}, &fakes.FakePluginStorage{}, storage.SimpleDirNameGeneratorFunc, nil)
 			err := pm.Add(context.Background(), p.ID, "3.2.0", testCompatOpts())
 			require.ErrorIs(t, err, plugins.ErrInstallCorePlugin)
 
@@ -30,6 +30,11 @@ func (d *ContainsBytesDetector) DetectAngular(moduleJs []byte) bool {
 	return bytes.Contains(moduleJs, d.Pattern)
 }
 
+// String returns the pattern of the detector.
+func (d *ContainsBytesDetector) String() string {
+	return string(d.Pattern)
+}
+
 // RegexDetector is an AngularDetector that returns true if the module.js content matches a regular expression.
 type RegexDetector struct {
 	Regex *regexp.Regexp
@@ -40,6 +45,11 @@ func (d *RegexDetector) DetectAngular(moduleJs []byte) bool {
 	return d.Regex.Match(moduleJs)
 }
 
+// String returns the regular expression of the detector.
+func (d *RegexDetector) String() string {
+	return d.Regex.String()
+}
+
 // DetectorsProvider can provide multiple AngularDetectors used for Angular detection.
 type DetectorsProvider interface {
 	// ProvideDetectors returns a slice of AngularDetector.
@@ -49,6 +59,10 @@ type DetectorsProvider interface {
 // StaticDetectorsProvider is a DetectorsProvider that always returns a pre-defined slice of AngularDetector.
 type StaticDetectorsProvider struct {
 	Detectors []AngularDetector
+
+	// Names contains the names of the Detectors, at the same index. It is used by ProvideNamedDetectors.
+	// Detectors without a name are named after their pattern.
+	Names []string
 }
 
 func (p *StaticDetectorsProvider) ProvideDetectors(_ context.Context) []AngularDetector {
@@ -0,0 +1,119 @@
+package angulardetector
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	_ NamedDetectorsProvider = &StaticDetectorsProvider{}
+	_ NamedDetectorsProvider = SequenceDetectorsProvider{}
+	_ NamedDetectorsProvider = ChainDetectorsProvider{}
+)
+
+// NamedDetector is an AngularDetector identified by a name.
+// The name is used to replace or suppress a detector when merging the detectors of multiple providers.
+type NamedDetector struct {
+	// Name identifies the detector.
+	Name string
+
+	// Detector is the wrapped AngularDetector. It is nil if Suppressed is true.
+	Detector AngularDetector
+
+	// Suppressed is true if the detector with the same name coming from lower-precedence providers should be removed.
+	Suppressed bool
+}
+
+// NamedDetectorsProvider can provide multiple NamedDetector.
+type NamedDetectorsProvider interface {
+	// ProvideNamedDetectors returns a slice of NamedDetector.
+	ProvideNamedDetectors(ctx context.Context) []NamedDetector
+}
+
+// ProvideNamedDetectors returns the static detectors, named after Names or, if they have no name, after their
+// pattern.
+func (p *StaticDetectorsProvider) ProvideNamedDetectors(_ context.Context) []NamedDetector {
+	r := namedDetectors(p.Detectors)
+	for i := range r {
+		if i < len(p.Names) && p.Names[i] != "" {
+			r[i].Name = p.Names[i]
+		}
+	}
+	return r
+}
+
+// ProvideNamedDetectors returns the first provided result that isn't empty, like ProvideDetectors.
+func (p SequenceDetectorsProvider) ProvideNamedDetectors(ctx context.Context) []NamedDetector {
+	for _, provider := range p {
+		if detectors := provideNamedDetectors(ctx, provider); len(detectors) > 0 {
+			return detectors
+		}
+	}
+	return nil
+}
+
+// ChainDetectorsProvider is a DetectorsProvider that wraps a slice of other DetectorsProvider, sorted by precedence
+// (the first one has the highest precedence), and merges their detectors.
+// If multiple providers return a detector with the same name, only the detector from the provider with the highest
+// precedence is kept. If that detector is suppressed, no detector with that name is returned.
+// Providers that do not implement NamedDetectorsProvider have their detectors named after their pattern.
+type ChainDetectorsProvider []DetectorsProvider
+
+func (p ChainDetectorsProvider) ProvideDetectors(ctx context.Context) []AngularDetector {
+	named := p.ProvideNamedDetectors(ctx)
+	if len(named) == 0 {
+		return nil
+	}
+	detectors := make([]AngularDetector, 0, len(named))
+	for _, d := range named {
+		detectors = append(detectors, d.Detector)
+	}
+	return detectors
+}
+
+// ProvideNamedDetectors returns the merged detectors of all the providers.
+// Detectors are returned in precedence order, and suppressed detectors are not returned.
+func (p ChainDetectorsProvider) ProvideNamedDetectors(ctx context.Context) []NamedDetector {
+	var r []NamedDetector
+	seen := map[string]struct{}{}
+	for _, provider := range p {
+		for _, d := range provideNamedDetectors(ctx, provider) {
+			if _, ok := seen[d.Name]; ok {
+				// Replaced or suppressed by a provider with higher precedence
+				continue
+			}
+			seen[d.Name] = struct{}{}
+			if d.Suppressed {
+				continue
+			}
+			r = append(r, d)
+		}
+	}
+	return r
+}
+
+// provideNamedDetectors returns the named detectors of the provided DetectorsProvider.
+// If the provider does not implement NamedDetectorsProvider, its detectors are named after their pattern.
+func provideNamedDetectors(ctx context.Context, provider DetectorsProvider) []NamedDetector {
+	if np, ok := provider.(NamedDetectorsProvider); ok {
+		return np.ProvideNamedDetectors(ctx)
+	}
+	return namedDetectors(provider.ProvideDetectors(ctx))
+}
+
+// namedDetectors names the provided detectors after their pattern, as returned by String().
+// Detectors that do not implement fmt.Stringer are named after their type and position.
+func namedDetectors(detectors []AngularDetector) []NamedDetector {
+	if len(detectors) == 0 {
+		return nil
+	}
+	r := make([]NamedDetector, 0, len(detectors))
+	for i, d := range detectors {
+		name := fmt.Sprintf("%T[%d]", d, i)
+		if s, ok := d.(fmt.Stringer); ok {
+			name = s.String()
+		}
+		r = append(r, NamedDetector{Name: name, Detector: d})
+	}
+	return r
+}
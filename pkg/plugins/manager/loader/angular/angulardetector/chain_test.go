@@ -0,0 +1,97 @@
+package angulardetector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNamedDetectorsProvider []NamedDetector
+
+func (p fakeNamedDetectorsProvider) ProvideDetectors(_ context.Context) []AngularDetector {
+	r := make([]AngularDetector, 0, len(p))
+	for _, d := range p {
+		if !d.Suppressed {
+			r = append(r, d.Detector)
+		}
+	}
+	return r
+}
+
+func (p fakeNamedDetectorsProvider) ProvideNamedDetectors(_ context.Context) []NamedDetector {
+	return p
+}
+
+func TestChainDetectorsProvider(t *testing.T) {
+	panelCtrl := &ContainsBytesDetector{Pattern: []byte("PanelCtrl")}
+	queryCtrl := &ContainsBytesDetector{Pattern: []byte("QueryCtrl")}
+	panelCtrlOverride := &ContainsBytesDetector{Pattern: []byte("MetricsPanelCtrl")}
+
+	for _, tc := range []struct {
+		name  string
+		chain ChainDetectorsProvider
+		exp   []AngularDetector
+	}{
+		{
+			name:  "empty",
+			chain: ChainDetectorsProvider{},
+			exp:   nil,
+		},
+		{
+			name: "merges detectors of all providers",
+			chain: ChainDetectorsProvider{
+				fakeNamedDetectorsProvider{{Name: "QueryCtrl", Detector: queryCtrl}},
+				&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl}},
+			},
+			exp: []AngularDetector{queryCtrl, panelCtrl},
+		},
+		{
+			name: "higher precedence provider replaces detector with the same name",
+			chain: ChainDetectorsProvider{
+				fakeNamedDetectorsProvider{{Name: "PanelCtrl", Detector: panelCtrlOverride}},
+				&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl, queryCtrl}},
+			},
+			exp: []AngularDetector{panelCtrlOverride, queryCtrl},
+		},
+		{
+			name: "higher precedence provider suppresses detector with the same name",
+			chain: ChainDetectorsProvider{
+				fakeNamedDetectorsProvider{{Name: "PanelCtrl", Suppressed: true}},
+				&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl, queryCtrl}},
+			},
+			exp: []AngularDetector{queryCtrl},
+		},
+		{
+			name: "higher precedence provider replaces named static detector",
+			chain: ChainDetectorsProvider{
+				fakeNamedDetectorsProvider{{Name: "QueryCtrl", Suppressed: true}},
+				&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl, queryCtrl}, Names: []string{"", "QueryCtrl"}},
+			},
+			exp: []AngularDetector{panelCtrl},
+		},
+		{
+			name: "lower precedence provider cannot replace detector",
+			chain: ChainDetectorsProvider{
+				&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl}},
+				fakeNamedDetectorsProvider{{Name: "PanelCtrl", Suppressed: true}},
+			},
+			exp: []AngularDetector{panelCtrl},
+		},
+		{
+			name: "sequence in chain falls back to the next provider",
+			chain: ChainDetectorsProvider{
+				fakeNamedDetectorsProvider{{Name: "PanelCtrl", Suppressed: true}},
+				SequenceDetectorsProvider{
+					fakeNamedDetectorsProvider{},
+					&StaticDetectorsProvider{Detectors: []AngularDetector{panelCtrl, queryCtrl}},
+				},
+			},
+			exp: []AngularDetector{queryCtrl},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, tc.chain.ProvideDetectors(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package angulardetector
+
+// jsTokenKind is the kind of a JavaScript token.
+type jsTokenKind int
+
+const (
+	// jsTokenIdentifier is an identifier or a keyword.
+	jsTokenIdentifier jsTokenKind = iota
+
+	// jsTokenString is a string or template literal. The value of the token is the unquoted content of the literal.
+	jsTokenString
+
+	// jsTokenNumber is a numeric literal.
+	jsTokenNumber
+
+	// jsTokenPunctuator is a single punctuation character, such as a parenthesis, a dot or a comma.
+	jsTokenPunctuator
+)
+
+// jsToken is a token of a JavaScript source.
+type jsToken struct {
+	kind  jsTokenKind
+	value string
+}
+
+// is returns true if the token has the provided kind and value.
+func (t jsToken) is(kind jsTokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+// regexPrecedingKeywords are the keywords after which a slash starts a regular expression literal rather than
+// a division.
+var regexPrecedingKeywords = map[string]struct{}{
+	"return": {}, "typeof": {}, "instanceof": {}, "in": {}, "of": {}, "new": {}, "delete": {}, "void": {},
+	"throw": {}, "case": {}, "do": {}, "else": {}, "yield": {}, "await": {},
+}
+
+// jsTokens splits the provided JavaScript source into tokens, skipping whitespace, comments and regular expression
+// literals. It is not a complete JavaScript lexer: it's only meant to tell code apart from comments and string
+// literals, even in minified bundles, so malformed sources do not return an error.
+// The escape sequences in string literals are not decoded, and template literals are returned as a single string
+// token, including their substitutions.
+func jsTokens(js []byte) []jsToken {
+	var tokens []jsToken
+	for i := 0; i < len(js); {
+		c := js[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '/' && i+1 < len(js) && js[i+1] == '/':
+			i = skipUntil(js, i+2, "\n")
+		case c == '/' && i+1 < len(js) && js[i+1] == '*':
+			i = skipUntil(js, i+2, "*/")
+		case c == '/' && slashStartsRegex(tokens):
+			i = skipRegex(js, i+1)
+		case c == '"' || c == '\'' || c == '`':
+			end := skipString(js, i+1, c)
+			value := js[i+1 : end]
+			if end < len(js) {
+				// Exclude the closing quote
+				end++
+			}
+			tokens = append(tokens, jsToken{kind: jsTokenString, value: string(value)})
+			i = end
+		case isIdentifierStart(c):
+			end := i + 1
+			for end < len(js) && isIdentifierPart(js[end]) {
+				end++
+			}
+			tokens = append(tokens, jsToken{kind: jsTokenIdentifier, value: string(js[i:end])})
+			i = end
+		case c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(js) && (isIdentifierPart(js[end]) || js[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, jsToken{kind: jsTokenNumber, value: string(js[i:end])})
+			i = end
+		default:
+			tokens = append(tokens, jsToken{kind: jsTokenPunctuator, value: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// skipUntil returns the index right after the first occurrence of terminator in js, starting from the provided index.
+// If there's no such occurrence, it returns len(js).
+func skipUntil(js []byte, i int, terminator string) int {
+	for ; i+len(terminator) <= len(js); i++ {
+		if string(js[i:i+len(terminator)]) == terminator {
+			return i + len(terminator)
+		}
+	}
+	return len(js)
+}
+
+// skipString returns the index of the closing quote of the string literal starting at the provided index, or len(js)
+// if the string literal is not terminated.
+func skipString(js []byte, i int, quote byte) int {
+	for ; i < len(js); i++ {
+		switch js[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		case '\n':
+			if quote != '`' {
+				// Unterminated string literal
+				return i
+			}
+		}
+	}
+	return len(js)
+}
+
+// skipRegex returns the index right after the regular expression literal starting at the provided index, flags
+// included.
+func skipRegex(js []byte, i int) int {
+	var inClass bool
+	for ; i < len(js); i++ {
+		switch js[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			// Unterminated regular expression literal
+			return i
+		case '/':
+			if inClass {
+				continue
+			}
+			i++
+			for i < len(js) && isIdentifierPart(js[i]) {
+				i++
+			}
+			return i
+		}
+	}
+	return len(js)
+}
+
+// slashStartsRegex returns true if a slash following the provided tokens starts a regular expression literal,
+// rather than being a division operator.
+func slashStartsRegex(tokens []jsToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	switch last.kind {
+	case jsTokenIdentifier:
+		_, ok := regexPrecedingKeywords[last.value]
+		return ok
+	case jsTokenPunctuator:
+		return last.value != ")" && last.value != "]" && last.value != "}"
+	}
+	return false
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,230 @@
+package angulardetector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ TokensDetector = &StructuralDetector{}
+
+// TokensDetector is an AngularDetector that matches the tokens of the js file, rather than its bytes.
+// The tokens of a JSFile are only split once, and shared by all the TokensDetectors matching it.
+type TokensDetector interface {
+	AngularDetector
+
+	// DetectAngularTokens returns true if the tokens of the provided js file match the detector.
+	DetectAngularTokens(f *JSFile) bool
+}
+
+// JSFile is a js file matched against multiple detectors. Its tokens are split the first time a TokensDetector
+// needs them. It is not safe for concurrent use.
+type JSFile struct {
+	// Content is the content of the js file.
+	Content []byte
+
+	tokens    []jsToken
+	tokenized bool
+}
+
+// NewJSFile returns a new JSFile with the provided content.
+func NewJSFile(js []byte) *JSFile {
+	return &JSFile{Content: js}
+}
+
+// jsTokens returns the tokens of the js file, splitting them on the first call.
+func (f *JSFile) jsTokens() []jsToken {
+	if !f.tokenized {
+		f.tokens = jsTokens(f.Content)
+		f.tokenized = true
+	}
+	return f.tokens
+}
+
+// DetectAngularFile returns true if the provided detector matches the provided js file. If the detector is a
+// TokensDetector, it matches the tokens of the js file, which are shared with the other TokensDetectors.
+func DetectAngularFile(d AngularDetector, f *JSFile) bool {
+	if td, ok := d.(TokensDetector); ok {
+		return td.DetectAngularTokens(f)
+	}
+	return d.DetectAngular(f.Content)
+}
+
+// StructuralCondition is the kind of structural condition matched by a StructuralDetector.
+type StructuralCondition string
+
+const (
+	// StructuralConditionImport matches modules importing the module with the provided name, either with an ES
+	// import or export statement, a require or dynamic import call, or an AMD define dependency.
+	StructuralConditionImport StructuralCondition = "import"
+
+	// StructuralConditionCall matches calls of the function with the provided name, which can be a dotted path
+	// (e.g.: angular.module).
+	StructuralConditionCall StructuralCondition = "call"
+
+	// StructuralConditionExtends matches classes extending the class with the provided name, also if the class is
+	// accessed through a namespace (e.g.: class A extends sdk.PanelCtrl).
+	StructuralConditionExtends StructuralCondition = "extends"
+
+	// StructuralConditionIdentifier matches the identifier with the provided name, outside comments and strings.
+	StructuralConditionIdentifier StructuralCondition = "identifier"
+)
+
+// ErrInvalidStructuralPattern is returned when a structural pattern cannot be parsed.
+var ErrInvalidStructuralPattern = errors.New("invalid structural pattern")
+
+// StructuralDetector is an AngularDetector that splits the js file into tokens, and matches a structural condition
+// against them, rather than substrings. Comments and unrelated string literals are ignored, which avoids the false
+// positives of ContainsBytesDetector and RegexDetector on minified bundles.
+type StructuralDetector struct {
+	// Condition is the kind of structural condition to match.
+	Condition StructuralCondition
+
+	// Value is the argument of the condition, such as the imported module name.
+	Value string
+
+	// path is Value split on dots, for the call condition.
+	path []string
+}
+
+// NewStructuralDetector returns a new StructuralDetector from the provided pattern, in the "condition:value" format
+// (e.g.: "import:app/plugins/sdk").
+// If the pattern cannot be parsed, it returns an error wrapping ErrInvalidStructuralPattern.
+func NewStructuralDetector(pattern string) (*StructuralDetector, error) {
+	condition, value, ok := strings.Cut(pattern, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%q: %w: must be in the condition:value format", pattern, ErrInvalidStructuralPattern)
+	}
+	d := &StructuralDetector{Condition: StructuralCondition(condition), Value: value}
+	switch d.Condition {
+	case StructuralConditionImport, StructuralConditionExtends, StructuralConditionIdentifier:
+	case StructuralConditionCall:
+		d.path = strings.Split(value, ".")
+	default:
+		return nil, fmt.Errorf("%q: %w: unknown condition %q", pattern, ErrInvalidStructuralPattern, condition)
+	}
+	return d, nil
+}
+
+// DetectAngular returns true if the tokens of the provided js file match the condition of the detector.
+// Use DetectAngularFile to match multiple detectors against the same js file, so it is only split into tokens once.
+func (d *StructuralDetector) DetectAngular(js []byte) bool {
+	return d.DetectAngularTokens(NewJSFile(js))
+}
+
+// DetectAngularTokens returns true if the tokens of the provided js file match the condition of the detector.
+func (d *StructuralDetector) DetectAngularTokens(f *JSFile) bool {
+	tokens := f.jsTokens()
+	for i := range tokens {
+		if d.matchAt(tokens, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAt returns true if the condition of the detector matches the tokens starting at index i.
+func (d *StructuralDetector) matchAt(tokens []jsToken, i int) bool {
+	switch d.Condition {
+	case StructuralConditionImport:
+		return d.matchImport(tokens, i)
+	case StructuralConditionCall:
+		return d.matchCall(tokens, i)
+	case StructuralConditionExtends:
+		return d.matchExtends(tokens, i)
+	case StructuralConditionIdentifier:
+		return tokens[i].is(jsTokenIdentifier, d.Value)
+	}
+	return false
+}
+
+// matchImport returns true if tokens[i] is the imported module name in an import statement, export statement,
+// require call, dynamic import call or AMD define call.
+func (d *StructuralDetector) matchImport(tokens []jsToken, i int) bool {
+	t := tokens[i]
+	if t.is(jsTokenIdentifier, "define") {
+		return matchAMDDependency(tokens, i, d.Value)
+	}
+	if !t.is(jsTokenString, d.Value) || i == 0 {
+		return false
+	}
+	prev := tokens[i-1]
+	if prev.is(jsTokenIdentifier, "from") || prev.is(jsTokenIdentifier, "import") {
+		// import x from "module"; export * from "module"; import "module"
+		return true
+	}
+	if prev.is(jsTokenPunctuator, "(") && i >= 2 {
+		// require("module"); import("module")
+		callee := tokens[i-2]
+		return callee.is(jsTokenIdentifier, "require") || callee.is(jsTokenIdentifier, "import")
+	}
+	return false
+}
+
+// matchAMDDependency returns true if tokens[i] is an AMD define call with the provided module name in its
+// dependencies: define(["module"], ...) or define("name", ["module"], ...).
+func matchAMDDependency(tokens []jsToken, i int, module string) bool {
+	if i > 0 && tokens[i-1].is(jsTokenPunctuator, ".") {
+		// Property access, such as x.define(...)
+		return false
+	}
+	j := i + 1
+	if j >= len(tokens) || !tokens[j].is(jsTokenPunctuator, "(") {
+		return false
+	}
+	j++
+	if j+1 < len(tokens) && tokens[j].kind == jsTokenString && tokens[j+1].is(jsTokenPunctuator, ",") {
+		// Named module
+		j += 2
+	}
+	if j >= len(tokens) || !tokens[j].is(jsTokenPunctuator, "[") {
+		return false
+	}
+	for j++; j < len(tokens) && !tokens[j].is(jsTokenPunctuator, "]"); j++ {
+		if tokens[j].is(jsTokenString, module) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCall returns true if the tokens starting at index i are a call of the function with the dotted path of the
+// detector.
+func (d *StructuralDetector) matchCall(tokens []jsToken, i int) bool {
+	if i > 0 && tokens[i-1].is(jsTokenPunctuator, ".") {
+		// Only match from the start of the path
+		return false
+	}
+	j := i
+	for k, name := range d.path {
+		if k > 0 {
+			if j >= len(tokens) || !tokens[j].is(jsTokenPunctuator, ".") {
+				return false
+			}
+			j++
+		}
+		if j >= len(tokens) || !tokens[j].is(jsTokenIdentifier, name) {
+			return false
+		}
+		j++
+	}
+	return j < len(tokens) && tokens[j].is(jsTokenPunctuator, "(")
+}
+
+// matchExtends returns true if the tokens starting at index i are an extends clause with the class of the detector,
+// optionally accessed through a namespace.
+func (d *StructuralDetector) matchExtends(tokens []jsToken, i int) bool {
+	if !tokens[i].is(jsTokenIdentifier, "extends") {
+		return false
+	}
+	j := i + 1
+	for j+2 < len(tokens) && tokens[j].kind == jsTokenIdentifier && tokens[j+1].is(jsTokenPunctuator, ".") {
+		j += 2
+	}
+	return j < len(tokens) && tokens[j].is(jsTokenIdentifier, d.Value)
+}
+
+// String returns the pattern of the detector, in the "condition:value" format.
+func (d *StructuralDetector) String() string {
+	return string(d.Condition) + ":" + d.Value
+}
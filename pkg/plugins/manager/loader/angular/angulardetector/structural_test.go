@@ -0,0 +1,202 @@
+package angulardetector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStructuralDetector(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		pattern  string
+		exp      *StructuralDetector
+		expError bool
+	}{
+		{
+			name:    "import",
+			pattern: "import:app/plugins/sdk",
+			exp:     &StructuralDetector{Condition: StructuralConditionImport, Value: "app/plugins/sdk"},
+		},
+		{
+			name:    "call",
+			pattern: "call:angular.module",
+			exp:     &StructuralDetector{Condition: StructuralConditionCall, Value: "angular.module", path: []string{"angular", "module"}},
+		},
+		{
+			name:    "extends",
+			pattern: "extends:PanelCtrl",
+			exp:     &StructuralDetector{Condition: StructuralConditionExtends, Value: "PanelCtrl"},
+		},
+		{
+			name:    "identifier",
+			pattern: "identifier:ctrl",
+			exp:     &StructuralDetector{Condition: StructuralConditionIdentifier, Value: "ctrl"},
+		},
+		{name: "no condition", pattern: "app/plugins/sdk", expError: true},
+		{name: "empty value", pattern: "import:", expError: true},
+		{name: "unknown condition", pattern: "contains:PanelCtrl", expError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewStructuralDetector(tc.pattern)
+			if tc.expError {
+				require.ErrorIs(t, err, ErrInvalidStructuralPattern)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, d)
+			require.Equal(t, tc.pattern, d.String())
+		})
+	}
+}
+
+func TestStructuralDetector(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pattern string
+		js      string
+		exp     bool
+	}{
+		// import
+		{name: "es import", pattern: "import:app/plugins/sdk", js: `import { PanelCtrl } from "app/plugins/sdk";`, exp: true},
+		{name: "es side effect import", pattern: "import:app/plugins/sdk", js: `import 'app/plugins/sdk';`, exp: true},
+		{name: "es export from", pattern: "import:app/plugins/sdk", js: "export * from `app/plugins/sdk`", exp: true},
+		{name: "require", pattern: "import:app/plugins/sdk", js: `var sdk = require("app/plugins/sdk");`, exp: true},
+		{name: "dynamic import", pattern: "import:app/plugins/sdk", js: `import("app/plugins/sdk").then(f)`, exp: true},
+		{
+			name:    "amd define",
+			pattern: "import:app/plugins/sdk",
+			js:      `define(["react","app/plugins/sdk","@grafana/data"],(function(e,t,n){return t.PanelCtrl}))`,
+			exp:     true,
+		},
+		{
+			name:    "named amd define",
+			pattern: "import:app/plugins/sdk",
+			js:      `define("my-plugin",["app/plugins/sdk"],function(e){})`,
+			exp:     true,
+		},
+		{
+			name:    "amd define without the dependency",
+			pattern: "import:app/plugins/sdk",
+			js:      `define(["react","@grafana/data"],(function(e,t){var n="app/plugins/sdk"}))`,
+			exp:     false,
+		},
+		{name: "import in a comment", pattern: "import:app/plugins/sdk", js: `// import { PanelCtrl } from "app/plugins/sdk";`, exp: false},
+		{name: "import in a block comment", pattern: "import:app/plugins/sdk", js: `/* require("app/plugins/sdk") */ f()`, exp: false},
+		{name: "module name in a string", pattern: "import:app/plugins/sdk", js: `var m = "app/plugins/sdk";`, exp: false},
+		{name: "other module", pattern: "import:app/plugins/sdk", js: `import { PanelPlugin } from "@grafana/data";`, exp: false},
+
+		// call
+		{name: "call", pattern: "call:angular.module", js: `angular.module("my-app", [])`, exp: true},
+		{name: "call with spaces", pattern: "call:angular.module", js: `angular . module ( "my-app" )`, exp: true},
+		{name: "call on another object", pattern: "call:angular.module", js: `window.angular.module("my-app")`, exp: false},
+		{name: "property access", pattern: "call:angular.module", js: `var m = angular.module;`, exp: false},
+		{name: "call in a string", pattern: "call:angular.module", js: `var s = 'angular.module("x")';`, exp: false},
+
+		// extends
+		{name: "extends", pattern: "extends:PanelCtrl", js: `class Ctrl extends PanelCtrl {}`, exp: true},
+		{name: "extends namespace", pattern: "extends:PanelCtrl", js: `class Ctrl extends sdk.PanelCtrl {}`, exp: true},
+		{name: "extends other class", pattern: "extends:PanelCtrl", js: `class Ctrl extends MetricsPanelCtrl {}`, exp: false},
+		{name: "extends in a comment", pattern: "extends:PanelCtrl", js: `/* extends PanelCtrl */ class Ctrl {}`, exp: false},
+
+		// identifier
+		{name: "identifier", pattern: "identifier:PanelCtrl", js: `t.PanelCtrl=n`, exp: true},
+		{name: "identifier in a string", pattern: "identifier:PanelCtrl", js: `var s="PanelCtrl"`, exp: false},
+		{name: "identifier in a regex", pattern: "identifier:PanelCtrl", js: `var r=/PanelCtrl/g`, exp: false},
+		{name: "identifier after a division", pattern: "identifier:PanelCtrl", js: `var x=a/2/PanelCtrl`, exp: true},
+		{name: "identifier prefix", pattern: "identifier:PanelCtrl", js: `var PanelCtrlProps`, exp: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewStructuralDetector(tc.pattern)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, d.DetectAngular([]byte(tc.js)))
+			require.Equal(t, tc.exp, DetectAngularFile(d, NewJSFile([]byte(tc.js))))
+		})
+	}
+}
+
+func TestDetectAngularFile(t *testing.T) {
+	importDetector, err := NewStructuralDetector("import:app/plugins/sdk")
+	require.NoError(t, err)
+	extendsDetector, err := NewStructuralDetector("extends:PanelCtrl")
+	require.NoError(t, err)
+
+	t.Run("tokens are split once", func(t *testing.T) {
+		f := NewJSFile([]byte(`import { PanelCtrl } from "app/plugins/sdk"; class Ctrl extends PanelCtrl {}`))
+		require.True(t, DetectAngularFile(importDetector, f))
+		require.True(t, f.tokenized)
+
+		// The next detectors match the tokens split by the first one
+		f.Content = nil
+		require.True(t, DetectAngularFile(extendsDetector, f))
+	})
+
+	t.Run("other detectors match the content", func(t *testing.T) {
+		f := NewJSFile([]byte(`// app/plugins/sdk`))
+		require.True(t, DetectAngularFile(&ContainsBytesDetector{Pattern: []byte("app/plugins/sdk")}, f))
+		require.False(t, f.tokenized)
+		require.False(t, DetectAngularFile(importDetector, f))
+	})
+}
+
+func TestJSTokens(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		js   string
+		exp  []jsToken
+	}{
+		{
+			name: "skips whitespace and comments",
+			js:   "a // b\n /* c */ d",
+			exp:  []jsToken{{kind: jsTokenIdentifier, value: "a"}, {kind: jsTokenIdentifier, value: "d"}},
+		},
+		{
+			name: "unquotes strings",
+			js:   `"a\"b" 'c' ` + "`d`",
+			exp: []jsToken{
+				{kind: jsTokenString, value: `a\"b`},
+				{kind: jsTokenString, value: "c"},
+				{kind: jsTokenString, value: "d"},
+			},
+		},
+		{
+			name: "skips regex literals",
+			js:   `return /a[/]b/gi;`,
+			exp:  []jsToken{{kind: jsTokenIdentifier, value: "return"}, {kind: jsTokenPunctuator, value: ";"}},
+		},
+		{
+			name: "division is not a regex",
+			js:   `a/b/c`,
+			exp: []jsToken{
+				{kind: jsTokenIdentifier, value: "a"},
+				{kind: jsTokenPunctuator, value: "/"},
+				{kind: jsTokenIdentifier, value: "b"},
+				{kind: jsTokenPunctuator, value: "/"},
+				{kind: jsTokenIdentifier, value: "c"},
+			},
+		},
+		{
+			name: "numbers",
+			js:   `x=1.5e3`,
+			exp: []jsToken{
+				{kind: jsTokenIdentifier, value: "x"},
+				{kind: jsTokenPunctuator, value: "="},
+				{kind: jsTokenNumber, value: "1.5e3"},
+			},
+		},
+		{
+			name: "unterminated string",
+			js:   `"abc`,
+			exp:  []jsToken{{kind: jsTokenString, value: "abc"}},
+		},
+		{
+			name: "unterminated comment",
+			js:   `a /* b`,
+			exp:  []jsToken{{kind: jsTokenIdentifier, value: "a"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, jsTokens([]byte(tc.js)))
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"regexp"
+	"sort"
 
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
@@ -22,33 +24,146 @@ type Inspector interface {
 type PatternsListInspector struct {
 	// DetectorsProvider returns the detectors that will be used by Inspect.
 	DetectorsProvider angulardetector.DetectorsProvider
+
+	// ScanAllJS makes Inspect scan all the JavaScript files of the plugin, rather than only module.js.
+	// module.js is always scanned first.
+	ScanAllJS bool
+
+	// MaxFileSize is the maximum number of bytes scanned for each file. 0 means no limit.
+	MaxFileSize int64
+
+	// MaxPluginBytes is the maximum number of bytes scanned for each plugin, across all files. 0 means no limit.
+	MaxPluginBytes int64
 }
 
-func (i *PatternsListInspector) Inspect(ctx context.Context, p *plugins.Plugin) (isAngular bool, err error) {
-	f, err := p.FS.Open("module.js")
+func (i *PatternsListInspector) Inspect(ctx context.Context, p *plugins.Plugin) (bool, error) {
+	matching, err := i.scan(p, i.DetectorsProvider.ProvideDetectors(ctx), true)
 	if err != nil {
-		if errors.Is(err, plugins.ErrFileNotExist) {
-			// We may not have a module.js for some backend plugins, so ignore the error if module.js does not exist
-			return false, nil
-		}
 		return false, err
 	}
+	return len(matching) > 0, nil
+}
+
+// MatchingDetectors returns all the detectors that match any of the plugin's scanned files.
+// Unlike Inspect, it does not stop at the first match.
+func (i *PatternsListInspector) MatchingDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.AngularDetector, error) {
+	detectors := i.DetectorsProvider.ProvideDetectors(ctx)
+	matching, err := i.scan(p, detectors, false)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]angulardetector.AngularDetector, 0, len(matching))
+	for _, di := range matching {
+		r = append(r, detectors[di])
+	}
+	return r, nil
+}
+
+// MatchingNamedDetectors is like MatchingDetectors, but returns the matching detectors alongside their names.
+// If the DetectorsProvider does not implement angulardetector.NamedDetectorsProvider, the detectors are named after
+// their pattern.
+func (i *PatternsListInspector) MatchingNamedDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error) {
+	named := angulardetector.ChainDetectorsProvider{i.DetectorsProvider}.ProvideNamedDetectors(ctx)
+	detectors := make([]angulardetector.AngularDetector, 0, len(named))
+	for _, d := range named {
+		detectors = append(detectors, d.Detector)
+	}
+	matching, err := i.scan(p, detectors, false)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]angulardetector.NamedDetector, 0, len(matching))
+	for _, di := range matching {
+		r = append(r, named[di])
+	}
+	return r, nil
+}
+
+// scan matches the plugin's files against the provided detectors and returns the indexes of the matching
+// detectors, in order. If firstMatch is true, it returns as soon as a detector matches.
+func (i *PatternsListInspector) scan(p *plugins.Plugin, detectors []angulardetector.AngularDetector, firstMatch bool) ([]int, error) {
+	files, err := i.filesToScan(p.FS)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]bool, len(detectors))
+	var r []int
+	budget := i.MaxPluginBytes
+	for _, fn := range files {
+		limit := i.MaxFileSize
+		if i.MaxPluginBytes > 0 {
+			if budget <= 0 {
+				break
+			}
+			if limit <= 0 || budget < limit {
+				limit = budget
+			}
+		}
+		b, err := readFile(p.FS, fn, limit)
+		if err != nil {
+			if errors.Is(err, plugins.ErrFileNotExist) {
+				// We may not have a module.js for some backend plugins, so ignore the error if module.js does not exist
+				continue
+			}
+			return nil, err
+		}
+		budget -= int64(len(b))
+		// The file is split into tokens at most once, and the tokens are shared by all the structural detectors
+		f := angulardetector.NewJSFile(b)
+		for di, d := range detectors {
+			if matched[di] || !angulardetector.DetectAngularFile(d, f) {
+				continue
+			}
+			matched[di] = true
+			r = append(r, di)
+			if firstMatch {
+				return r, nil
+			}
+		}
+	}
+	return r, nil
+}
+
+// filesToScan returns the files that should be scanned by Inspect, in order.
+func (i *PatternsListInspector) filesToScan(fsys plugins.FS) ([]string, error) {
+	files := []string{"module.js"}
+	if !i.ScanAllJS {
+		return files, nil
+	}
+	allFiles, err := fsys.Files()
+	if err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	sort.Strings(allFiles)
+	for _, fn := range allFiles {
+		if fn == "module.js" || path.Ext(fn) != ".js" {
+			continue
+		}
+		files = append(files, fn)
+	}
+	return files, nil
+}
+
+// readFile reads at most limit bytes from the file with the provided name. If limit is 0, the whole file is read.
+func readFile(fsys plugins.FS, name string, limit int64) (b []byte, err error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if closeErr := f.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("close module.js: %w", closeErr)
+			err = fmt.Errorf("close %s: %w", name, closeErr)
 		}
 	}()
-	b, err := io.ReadAll(f)
-	if err != nil {
-		return false, fmt.Errorf("module.js readall: %w", err)
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, limit)
 	}
-	for _, d := range i.DetectorsProvider.ProvideDetectors(ctx) {
-		if d.DetectAngular(b) {
-			isAngular = true
-			break
-		}
+	b, err = io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s readall: %w", name, err)
 	}
-	return
+	return b, nil
 }
 
 // defaultDetectors contains all the detectors to detect Angular plugins.
@@ -65,10 +180,25 @@ var defaultDetectors = []angulardetector.AngularDetector{
 	&angulardetector.RegexDetector{Regex: regexp.MustCompile(`["']QueryCtrl["']`)},
 }
 
+// defaultDetectorsNames contains the names of defaultDetectors, at the same index.
+// They are the names of the same patterns returned by GCOM, so the dynamic and provisioned patterns can replace or
+// suppress the static ones.
+var defaultDetectorsNames = []string{
+	"PanelCtrl",
+	"ConfigCtrl",
+	"app/plugins/sdk",
+	"angular.isNumber(",
+	"editor.html",
+	"ctrl.annotation",
+	"getLegacyAngularInjector",
+
+	"QueryCtrl",
+}
+
 // NewDefaultStaticDetectorsProvider returns a new StaticDetectorsProvider with the default (static, hardcoded) angular
-// detection patterns (defaultDetectors)
+// detection patterns (defaultDetectors), named like the GCOM patterns (defaultDetectorsNames)
 func NewDefaultStaticDetectorsProvider() angulardetector.DetectorsProvider {
-	return &angulardetector.StaticDetectorsProvider{Detectors: defaultDetectors}
+	return &angulardetector.StaticDetectorsProvider{Detectors: defaultDetectors, Names: defaultDetectorsNames}
 }
 
 // NewStaticInspector returns the default Inspector, which is a PatternsListInspector that only uses the
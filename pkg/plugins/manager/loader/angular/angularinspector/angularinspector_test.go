@@ -2,6 +2,7 @@ package angularinspector
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"testing"
 
@@ -82,6 +83,147 @@ func TestPatternsListInspector(t *testing.T) {
 	}
 }
 
+func TestPatternsListInspectorScanScope(t *testing.T) {
+	plugin := &plugins.Plugin{
+		FS: plugins.NewInMemoryFS(map[string][]byte{
+			"module.js":      []byte(`console.log("react")`),
+			"module.js.map":  []byte(`PanelCtrl`),
+			"components.js":  []byte(`import { MetricsPanelCtrl } from 'grafana/app/plugins/sdk';`),
+			"img/logo.svg":   []byte(`PanelCtrl`),
+			"dist/editor.js": []byte(`console.log("react")`),
+		}),
+	}
+
+	for _, tc := range []struct {
+		name      string
+		inspector *PatternsListInspector
+		exp       bool
+	}{
+		{
+			name:      "module.js only",
+			inspector: &PatternsListInspector{},
+			exp:       false,
+		},
+		{
+			name:      "all js files",
+			inspector: &PatternsListInspector{ScanAllJS: true},
+			exp:       true,
+		},
+		{
+			name:      "file size limit",
+			inspector: &PatternsListInspector{ScanAllJS: true, MaxFileSize: 10},
+			exp:       false,
+		},
+		{
+			name:      "plugin bytes budget exhausted",
+			inspector: &PatternsListInspector{ScanAllJS: true, MaxPluginBytes: 20},
+			exp:       false,
+		},
+		{
+			name:      "plugin bytes budget not exhausted",
+			inspector: &PatternsListInspector{ScanAllJS: true, MaxPluginBytes: 1024},
+			exp:       true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.inspector.DetectorsProvider = NewDefaultStaticDetectorsProvider()
+			r, err := tc.inspector.Inspect(context.Background(), plugin)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, r)
+		})
+	}
+}
+
+func TestPatternsListInspectorMatchingDetectors(t *testing.T) {
+	plugin := &plugins.Plugin{
+		FS: plugins.NewInMemoryFS(map[string][]byte{
+			"module.js":     []byte(`PanelCtrl`),
+			"components.js": []byte(`import { MetricsPanelCtrl } from 'grafana/app/plugins/sdk'; "QueryCtrl"`),
+		}),
+	}
+	inspector := &PatternsListInspector{DetectorsProvider: NewDefaultStaticDetectorsProvider(), ScanAllJS: true}
+	detectors, err := inspector.MatchingDetectors(context.Background(), plugin)
+	require.NoError(t, err)
+	patterns := make([]string, 0, len(detectors))
+	for _, d := range detectors {
+		patterns = append(patterns, d.(fmt.Stringer).String())
+	}
+	require.Equal(t, []string{"PanelCtrl", "app/plugins/sdk", `["']QueryCtrl["']`}, patterns)
+
+	t.Run("named", func(t *testing.T) {
+		inspector := &PatternsListInspector{
+			DetectorsProvider: angulardetector.ChainDetectorsProvider{namedDetectorsProvider{
+				{Name: "PanelCtrl", Detector: &angulardetector.ContainsBytesDetector{Pattern: []byte("PanelCtrl")}},
+				{Name: "QueryCtrl", Detector: &angulardetector.ContainsBytesDetector{Pattern: []byte("QueryCtrl")}},
+				{Name: "Suppressed", Suppressed: true},
+			}},
+			ScanAllJS: true,
+		}
+		named, err := inspector.MatchingNamedDetectors(context.Background(), plugin)
+		require.NoError(t, err)
+		names := make([]string, 0, len(named))
+		for _, d := range named {
+			names = append(names, d.Name)
+		}
+		require.Equal(t, []string{"PanelCtrl", "QueryCtrl"}, names)
+	})
+}
+
+func TestPatternsListInspectorSharesTokens(t *testing.T) {
+	plugin := &plugins.Plugin{
+		FS: plugins.NewInMemoryFS(map[string][]byte{
+			"module.js":     []byte(`import { PanelCtrl } from "app/plugins/sdk"`),
+			"components.js": []byte(`class Ctrl extends PanelCtrl {}`),
+		}),
+	}
+	detectors := []*fakeTokensDetector{{}, {}}
+	inspector := &PatternsListInspector{
+		DetectorsProvider: &angulardetector.StaticDetectorsProvider{
+			Detectors: []angulardetector.AngularDetector{detectors[0], detectors[1]},
+		},
+		ScanAllJS: true,
+	}
+	_, err := inspector.MatchingDetectors(context.Background(), plugin)
+	require.NoError(t, err)
+
+	// Each file is shared by the detectors
+	require.Len(t, detectors[0].files, 2)
+	require.Len(t, detectors[1].files, 2)
+	for i, f := range detectors[0].files {
+		require.Same(t, f, detectors[1].files[i])
+	}
+	require.NotSame(t, detectors[0].files[0], detectors[0].files[1])
+}
+
+// fakeTokensDetector is an angulardetector.TokensDetector that records the files it is matched against.
+type fakeTokensDetector struct {
+	files []*angulardetector.JSFile
+}
+
+func (d *fakeTokensDetector) DetectAngular(_ []byte) bool {
+	panic("the tokens of the files should be matched")
+}
+
+func (d *fakeTokensDetector) DetectAngularTokens(f *angulardetector.JSFile) bool {
+	d.files = append(d.files, f)
+	return false
+}
+
+// namedDetectorsProvider is an angulardetector.NamedDetectorsProvider that returns a fixed slice of NamedDetector.
+type namedDetectorsProvider []angulardetector.NamedDetector
+
+func (p namedDetectorsProvider) ProvideDetectors(_ context.Context) []angulardetector.AngularDetector {
+	r := make([]angulardetector.AngularDetector, 0, len(p))
+	for _, d := range p {
+		r = append(r, d.Detector)
+	}
+	return r
+}
+
+func (p namedDetectorsProvider) ProvideNamedDetectors(_ context.Context) []angulardetector.NamedDetector {
+	return p
+}
+
 func TestDefaultStaticDetectorsInspector(t *testing.T) {
 	// Tests the default hardcoded angular patterns
 
@@ -55,6 +55,8 @@ type Plugin struct {
 	BaseURL string
 
 	AngularDetected bool
+	// angularMu protects AngularDetected once the plugin has been registered, as it can be re-evaluated at runtime.
+	angularMu sync.RWMutex
 
 	ExternalService *oauth.ExternalService
 
@@ -447,6 +449,8 @@ type PluginClient interface {
 }
 
 func (p *Plugin) ToDTO() PluginDTO {
+	p.angularMu.RLock()
+	defer p.angularMu.RUnlock()
 	return PluginDTO{
 		logger:            p.Logger(),
 		fs:                p.FS,
@@ -467,6 +471,21 @@ func (p *Plugin) ToDTO() PluginDTO {
 	}
 }
 
+// IsAngularDetected returns true if the plugin has been detected as using Angular.
+func (p *Plugin) IsAngularDetected() bool {
+	p.angularMu.RLock()
+	defer p.angularMu.RUnlock()
+	return p.AngularDetected
+}
+
+// SetAngularDetected updates AngularDetected in a concurrency-safe way.
+// It should be used instead of setting AngularDetected directly once the plugin has been registered.
+func (p *Plugin) SetAngularDetected(angularDetected bool) {
+	p.angularMu.Lock()
+	defer p.angularMu.Unlock()
+	p.AngularDetected = angularDetected
+}
+
 func (p *Plugin) StaticRoute() *StaticRoute {
 	if p.IsCorePlugin() {
 		return nil
@@ -35,6 +35,29 @@ func NewClient(skipTLSVerify bool, logger log.PrettyLogger) *Client {
 	}
 }
 
+// NewClientWithTransport returns a new Client that sends the requests to the plugin repository API, served by
+// apiHost, through the provided transport. The plugin archives are downloaded, and the requests to the other hosts
+// are sent, like NewClient does.
+func NewClientWithTransport(transport http.RoundTripper, apiHost string, skipTLSVerify bool, logger log.PrettyLogger) *Client {
+	c := NewClient(skipTLSVerify, logger)
+	c.httpClient.Transport = hostTransport{host: apiHost, transport: transport, fallback: c.httpClient.Transport}
+	return c
+}
+
+// hostTransport sends the requests to host through transport, and all the other requests through fallback.
+type hostTransport struct {
+	host      string
+	transport http.RoundTripper
+	fallback  http.RoundTripper
+}
+
+func (t hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host {
+		return t.transport.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
 func (c *Client) Download(_ context.Context, pluginZipURL, checksum string, compatOpts CompatOpts) (*PluginArchive, error) {
 	// Create temp file for downloading zip file
 	tmpFile, err := os.CreateTemp("", "*.zip")
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 
@@ -36,12 +37,20 @@ type ManagerCfg struct {
 	SkipTLSVerify bool
 	BaseURL       string
 	Logger        log.PrettyLogger
+
+	// Transport is the transport used to send the requests to the plugin repository API, on the host of BaseURL.
+	// The plugin archives are always downloaded with the default transport.
+	Transport http.RoundTripper
 }
 
 func NewManager(cfg ManagerCfg) *Manager {
+	client := NewClient(cfg.SkipTLSVerify, cfg.Logger)
+	if u, err := url.Parse(cfg.BaseURL); err == nil && cfg.Transport != nil {
+		client = NewClientWithTransport(cfg.Transport, u.Host, cfg.SkipTLSVerify, cfg.Logger)
+	}
 	return &Manager{
 		baseURL: cfg.BaseURL,
-		client:  NewClient(cfg.SkipTLSVerify, cfg.Logger),
+		client:  client,
 		log:     cfg.Logger,
 	}
 }
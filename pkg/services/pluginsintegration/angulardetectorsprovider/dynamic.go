@@ -5,52 +5,188 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
-	"net/http"
-	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/log"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/remoterules"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-// backgroundJobInterval is the interval that passes between background job runs.
+// backgroundJobInterval is the default interval that passes between background job runs,
+// used if no refresh interval is configured.
 // It can be overwritten in tests.
 var backgroundJobInterval = time.Hour * 1
 
+const (
+	// refreshLockActionName is the name of the server lock used to fetch the patterns from a single instance.
+	refreshLockActionName = "angular patterns refresh"
+
+	// storeLockActionName is the name of the server lock held by the instance changing the stored patterns,
+	// versions, history or pin, so their read-modify-writes are not interleaved across instances.
+	storeLockActionName = "angular patterns store"
+
+	// storeLockTimeout is the time after which the store lock is considered released, if the instance holding it
+	// stopped before releasing it. It's longer than the longest GCOM fetch, retries included.
+	storeLockTimeout = time.Minute * 5
+
+	// feedName identifies the angular patterns feed. It is used as the prefix of the fetch metrics and as the caller
+	// in the shared GCOM client metrics.
+	feedName = "angular_patterns"
+)
+
+// patternsFeed returns the remoterules.FeedConfig of the angular detection patterns.
+func patternsFeed(cfg *config.Cfg) remoterules.FeedConfig {
+	return remoterules.FeedConfig{
+		Name:             feedName,
+		Description:      "angular detection patterns",
+		Path:             gcomAngularPatternsPath,
+		SignaturePath:    gcomAngularPatternsSignaturePath,
+		RequireSignature: cfg.AngularDetection.RequirePatternsSignature,
+	}
+}
+
+// CacheSource is the source the cached angular detectors have been populated from.
+type CacheSource string
+
+const (
+	// CacheSourceNone means that the cache has not been populated yet.
+	CacheSourceNone CacheSource = ""
+
+	// CacheSourceDatabase means that the cache has been populated from the database.
+	CacheSourceDatabase CacheSource = "database"
+
+	// CacheSourceRemote means that the cache has been populated from GCOM.
+	CacheSourceRemote CacheSource = "remote"
+
+	// CacheSourceRemoteCache means that the cache has been populated from the remote cache, shared by all the
+	// instances of a high availability setup.
+	CacheSourceRemoteCache CacheSource = "remote_cache"
+)
+
+// ErrPatternsLocked is returned when the stored angular patterns cannot be changed because another instance is
+// changing them.
+var ErrPatternsLocked = errors.New("angular patterns are being changed by another instance")
+
+// refreshLock allows a single Grafana instance at a time to execute a function.
+// It is implemented by serverlock.ServerLockService.
+type refreshLock interface {
+	LockAndExecute(ctx context.Context, actionName string, maxInterval time.Duration, fn func(ctx context.Context)) error
+	LockExecuteAndRelease(ctx context.Context, actionName string, maxInterval time.Duration, fn func(ctx context.Context)) error
+}
+
 // Dynamic is an angulardetector.DetectorsProvider that calls GCOM to get Angular detection patterns,
 // converts them to detectors and caches them for all future calls.
 // It also provides a background service that will periodically refresh the patterns from GCOM.
+// If the patterns are cached in the remote cache, only one instance at a time refreshes the patterns from GCOM, and
+// the other instances read them from the remote cache.
+// If the cached patterns have not been updated for longer than the configured max age, the static detectors are
+// merged into the cached detectors.
 // If the feature flag FlagPluginsDynamicAngularDetectionPatterns is disabled, the background service is disabled.
 type Dynamic struct {
 	log      log.Logger
 	features featuremgmt.FeatureToggles
+	cfg      *config.Cfg
+	metrics  *metrics
 
-	httpClient http.Client
-	baseURL    string
+	// client fetches the patterns from GCOM, retrying transient errors and protecting GCOM with a circuit breaker.
+	client *remoterules.Client[gcomPatternsResponse]
 
 	// store is the underlying angular patterns store used as a cache.
 	store angularpatternsstore.Service
 
+	// lock makes sure that only one instance fetches the patterns from GCOM, and that only one instance changes the
+	// stored patterns at a time, when the patterns are cached in the remote cache.
+	// It is nil if the patterns are cached in the database.
+	lock refreshLock
+
+	// static provides the static detectors, which are merged into the cached detectors when they are stale.
+	static angulardetector.DetectorsProvider
+
+	// stale is true if the cached detectors have been reported as stale.
+	// It is used to log and update the metrics only when the staleness changes.
+	stale atomic.Bool
+
 	// detectors contains the cached angular detectors, which are created from the remote angular patterns.
 	// mux should be acquired before reading from/writing to this field.
 	detectors []angulardetector.AngularDetector
 
+	// patterns contains the cached angular patterns the detectors have been created from.
+	// mux should be acquired before reading from/writing to this field.
+	patterns GCOMPatterns
+
+	// namedDetectors contains the cached angular detectors, named after the patterns they have been created from.
+	// They are created once, when the cached patterns change, so they are not compiled again on each inspection.
+	// mux should be acquired before reading from/writing to this field.
+	namedDetectors []angulardetector.NamedDetector
+
+	// namedDetectorsPatterns contains the patterns namedDetectors have been created from, at the same index.
+	// mux should be acquired before reading from/writing to this field.
+	namedDetectorsPatterns GCOMPatterns
+
+	// provenance contains the provenance of the cached angular patterns.
+	// mux should be acquired before reading from/writing to this field.
+	provenance Provenance
+
+	// cacheSource is the source the cached detectors have been populated from.
+	// mux should be acquired before reading from/writing to this field.
+	cacheSource CacheSource
+
+	// skipped is the number of cached patterns that could not be converted to detectors because their type is
+	// unknown. If it's greater than zero, the cached patterns are not fully understood.
+	// mux should be acquired before reading from/writing to this field.
+	skipped int
+
+	// schemaStatus contains information about the schema of the latest patterns fetched from GCOM.
+	// mux should be acquired before reading from/writing to this field.
+	schemaStatus SchemaStatus
+
+	// lastSuccess is the time when the cached patterns have been last confirmed by GCOM.
+	// mux should be acquired before reading from/writing to this field.
+	lastSuccess time.Time
+
+	// lastAttempt is the time when this instance has last tried to update the patterns, successfully or not.
+	// It is used by RefreshIfStale to not call GCOM again until the next refresh is due if the last attempt failed.
+	// mux should be acquired before reading from/writing to this field.
+	lastAttempt time.Time
+
 	// mux is the mutex used to read/write the cached detectors in a concurrency-safe way.
 	mux sync.RWMutex
+
+	// updateMux serializes the changes to the stored patterns and pin: updates from GCOM, rollbacks and pin releases.
+	// It must be acquired before mux. See withStoreLock.
+	updateMux sync.Mutex
+
+	// subscribers are notified every time the cached detectors change.
+	subscribers subscribers
 }
 
-func ProvideDynamic(cfg *config.Cfg, store angularpatternsstore.Service, features featuremgmt.FeatureToggles) (*Dynamic, error) {
+func ProvideDynamic(cfg *config.Cfg, store angularpatternsstore.Service, gcomClient *gcomclient.Client, serverLock *serverlock.ServerLockService, features featuremgmt.FeatureToggles, registerer prometheus.Registerer) (*Dynamic, error) {
 	d := &Dynamic{
-		log:        log.New("plugin.angulardetectorsprovider.dynamic"),
-		features:   features,
-		store:      store,
-		httpClient: makeHttpClient(),
-		baseURL:    cfg.GrafanaComURL,
+		log:      log.New("plugin.angulardetectorsprovider.dynamic"),
+		features: features,
+		cfg:      cfg,
+		metrics:  newMetrics(registerer),
+		store:    store,
+		client: remoterules.NewClient[gcomPatternsResponse](
+			patternsFeed(cfg), cfg.GrafanaComURL, gcomClient,
+			remoterules.DecoderFunc[gcomPatternsResponse](parseGCOMPatterns), registerer,
+		),
+		static:       angularinspector.NewDefaultStaticDetectorsProvider(),
+		schemaStatus: SchemaStatus{SupportedSchemaVersion: gcomPatternsSchemaVersion},
+	}
+	if cfg.AngularDetection.CacheBackend == setting.AngularPatternsCacheBackendRemoteCache && serverLock != nil {
+		d.lock = serverLock
 	}
 	if d.IsDisabled() {
 		// Do not attempt to restore if the background service is disabled (no feature flag)
@@ -69,95 +205,254 @@ func ProvideDynamic(cfg *config.Cfg, store angularpatternsstore.Service, feature
 }
 
 // patternsToDetectors converts a slice of gcomPattern into a slice of angulardetector.AngularDetector, by calling
-// angularDetector() on each gcomPattern.
-func (d *Dynamic) patternsToDetectors(patterns GCOMPatterns) ([]angulardetector.AngularDetector, error) {
-	var finalErr error
-	detectors := make([]angulardetector.AngularDetector, 0, len(patterns))
-	for _, pattern := range patterns {
-		ad, err := pattern.angularDetector()
-		if err != nil {
-			// Fail silently in case of an errUnknownPatternType.
-			// This allows us to introduce new pattern types without breaking old Grafana versions
-			if errors.Is(err, errUnknownPatternType) {
-				d.log.Debug("Unknown angular pattern", "name", pattern.Name, "type", pattern.Type, "error", err)
-				continue
-			}
-			// Other error, do not ignore it
-			finalErr = errors.Join(finalErr, err)
-		}
-		detectors = append(detectors, ad)
-	}
-	if finalErr != nil {
-		return nil, finalErr
-	}
-	return detectors, nil
+// angularDetector() on each gcomPattern. The number of patterns skipped because of an unknown type is returned
+// alongside the detectors.
+func (d *Dynamic) patternsToDetectors(patterns GCOMPatterns) ([]angulardetector.AngularDetector, int, error) {
+	detectors, skipped, err := patternsToDetectors(d.log, patterns)
+	d.metrics.unknownTypesSkipped.Add(float64(skipped))
+	return detectors, skipped, err
 }
 
-// fetch fetches the angular patterns from GCOM and returns them as GCOMPatterns.
+// fetch fetches the angular patterns from GCOM and returns them alongside their schema version and the
+// HTTP cache validators of the response.
+// If validators are not empty, a conditional request is made, and remoterules.ErrNotModified is returned if
+// the patterns have not been modified.
 // Call detectors() on the returned value to get the corresponding detectors.
-func (d *Dynamic) fetch(ctx context.Context) (GCOMPatterns, error) {
-	st := time.Now()
-
-	reqURL, err := url.JoinPath(d.baseURL, gcomAngularPatternsPath)
+func (d *Dynamic) fetch(ctx context.Context, validators angularpatternsstore.CacheValidators) (gcomPatternsResponse, angularpatternsstore.CacheValidators, error) {
+	r, err := d.client.Fetch(ctx, validators)
 	if err != nil {
-		return nil, fmt.Errorf("url joinpath: %w", err)
+		return gcomPatternsResponse{}, r.Validators, err
 	}
+	d.log.Debug("Fetched dynamic angular detection patterns", "patterns", len(r.Rules.Patterns), "schemaVersion", r.Rules.SchemaVersion)
+	return r.Rules, r.Validators, nil
+}
 
-	d.log.Debug("Fetching dynamic angular detection patterns", "url", reqURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("new request with context: %w", err)
-	}
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("http do: %w", err)
+// newProvenance returns the Provenance of the provided JSON-encoded patterns fetched from GCOM at the provided time,
+// with the provided stored schema version.
+func (d *Dynamic) newProvenance(rawPatterns []byte, fetchedAt time.Time, schemaVersion int) Provenance {
+	// URL can only fail if the base url is invalid, in which case the patterns cannot be fetched anyway
+	source, _ := d.client.URL()
+	if schemaVersion == 0 {
+		// Patterns stored without a schema version are plain lists of patterns, which are schema version 1
+		schemaVersion = 1
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			d.log.Error("Response body close error", "error", err)
-		}
-	}()
-	var out GCOMPatterns
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, fmt.Errorf("json decode: %w", err)
+	return Provenance{
+		Source:        source,
+		FetchedAt:     fetchedAt,
+		Hash:          angularpatternsstore.PatternsHash(rawPatterns),
+		SchemaVersion: schemaVersion,
 	}
-	d.log.Debug("Fetched dynamic angular detection patterns", "patterns", len(out), "duration", time.Since(st))
-	return out, nil
+}
+
+// VersionProvenance returns the Provenance of a stored patterns version.
+func (d *Dynamic) VersionProvenance(v angularpatternsstore.PatternsVersion) Provenance {
+	return d.newProvenance(v.Patterns, v.CreatedAt, v.SchemaVersion)
 }
 
 // updateDetectors fetches the patterns from GCOM, converts them to detectors,
 // stores the patterns in the database and update the cached detectors.
+// GCOM is not called if the circuit breaker is open.
+// If the fetched patterns are not fully understood (newer schema version or unknown pattern types), the cached
+// patterns are kept as long as they are fully understood, rather than being replaced by a partially-degraded set.
+// The cached detectors are only locked to be replaced, so they can still be read while GCOM is being called.
+// The store lock is held for the whole update, see withStoreLock.
 func (d *Dynamic) updateDetectors(ctx context.Context) error {
+	return d.withStoreLock(ctx, d.fetchAndStore)
+}
+
+// fetchAndStore implements updateDetectors. It must be called with the store lock held.
+func (d *Dynamic) fetchAndStore(ctx context.Context) error {
 	// Fetch patterns from GCOM
-	d.mux.Lock()
-	defer d.mux.Unlock()
-	patterns, err := d.fetch(ctx)
+	validators, err := d.cacheValidators(ctx)
 	if err != nil {
+		return fmt.Errorf("cache validators: %w", err)
+	}
+	resp, newValidators, err := d.fetch(ctx, validators)
+	if err != nil && !errors.Is(err, remoterules.ErrNotModified) {
 		return fmt.Errorf("fetch: %w", err)
 	}
+	if errors.Is(err, remoterules.ErrNotModified) {
+		// Patterns are up-to-date, keep the cached detectors and only mark them as fresh
+		return d.keepCachedPatterns(ctx)
+	}
+
+	patterns := resp.Patterns
+
+	// Handle empty responses according to the configured policy
+	if len(patterns) == 0 {
+		policy := d.cfg.AngularDetection.EmptyPatternsPolicy
+		d.metrics.emptyResponses.WithLabelValues(string(policy)).Inc()
+		if policy != setting.AngularEmptyPatternsPolicyClear {
+			d.log.Warn("GCOM returned no angular patterns, keeping the previous patterns", "policy", policy)
+			return d.keepCachedPatterns(ctx)
+		}
+		d.log.Warn("GCOM returned no angular patterns, clearing the cached patterns", "policy", policy)
+	}
+
+	// Do not replace a pinned version, unless GCOM returned a newer version
+	pinned, err := d.isPinned(ctx, patterns)
+	if err != nil {
+		return fmt.Errorf("is pinned: %w", err)
+	}
+	if pinned {
+		d.log.Debug("Angular patterns version is pinned, not updating patterns")
+		return d.keepCachedPatterns(ctx)
+	}
 
 	// Convert the patterns to detectors
-	newDetectors, err := d.patternsToDetectors(patterns)
+	newDetectors, skipped, err := d.patternsToDetectors(patterns)
 	if err != nil {
 		return fmt.Errorf("patterns convert to detectors: %w", err)
 	}
 
+	// Keep the cached patterns if they are fully understood and the new ones are not
+	schemaStatus := SchemaStatus{
+		SchemaVersion:          resp.SchemaVersion,
+		SupportedSchemaVersion: gcomPatternsSchemaVersion,
+		SkippedPatterns:        skipped,
+	}
+	d.metrics.schemaSkipped.Set(float64(skipped))
+	if schemaStatus.HasVersionSkew() {
+		d.log.Warn(
+			"Angular patterns are not fully supported by this version of Grafana",
+			"schemaVersion", resp.SchemaVersion, "supportedSchemaVersion", gcomPatternsSchemaVersion, "skipped", skipped,
+		)
+		d.mux.RLock()
+		keepPrevious := len(d.patterns) > 0 && d.skipped == 0
+		d.mux.RUnlock()
+		if keepPrevious {
+			d.log.Warn("Keeping the previous, fully supported, angular patterns")
+			schemaStatus.KeptPrevious = true
+			d.mux.Lock()
+			d.schemaStatus = schemaStatus
+			d.mux.Unlock()
+			return d.keepCachedPatterns(ctx)
+		}
+	}
+	d.mux.Lock()
+	d.schemaStatus = schemaStatus
+	d.mux.Unlock()
+
 	// Update store only if the patterns can be converted to detectors
-	if err := d.store.Set(ctx, patterns); err != nil {
+	fetchedAt := time.Now()
+	if err := d.store.SetWithSchemaVersion(ctx, patterns, resp.SchemaVersion); err != nil {
 		return fmt.Errorf("store set: %w", err)
 	}
+	if err := d.store.SetCacheValidators(ctx, newValidators); err != nil {
+		return fmt.Errorf("store set cache validators: %w", err)
+	}
+	rawPatterns, err := json.Marshal(patterns)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
 
 	// Update cached detectors
+	provenance := d.newProvenance(rawPatterns, fetchedAt, resp.SchemaVersion)
+	namedDetectors, namedDetectorsPatterns := compilePatterns(d.log, patterns)
+	d.mux.Lock()
 	d.detectors = newDetectors
+	d.patterns = patterns
+	d.namedDetectors = namedDetectors
+	d.namedDetectorsPatterns = namedDetectorsPatterns
+	d.skipped = skipped
+	d.provenance = provenance
+	d.cacheSource = CacheSourceRemote
+	d.lastSuccess = fetchedAt
+	d.mux.Unlock()
+	d.metrics.patternsLoaded.Set(float64(len(newDetectors)))
+	d.client.Metrics.LastSuccess.SetToCurrentTime()
+	d.subscribers.publish(DetectorsUpdated{Provider: ProviderDynamic, UpdatedAt: fetchedAt})
 	return nil
 }
 
-// setDetectorsFromCache sets the in-memory detectors from the patterns in the store.
-// The caller must Lock d.mux before calling this function.
-func (d *Dynamic) setDetectorsFromCache(ctx context.Context) error {
+// withStoreLock calls fn while holding updateMux and, if the patterns are shared with other instances, the store server
+// lock, so the changes to the stored patterns, versions, history and pin are serialized across all the instances.
+// It returns ErrPatternsLocked if another instance holds the store server lock.
+func (d *Dynamic) withStoreLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	d.updateMux.Lock()
+	defer d.updateMux.Unlock()
+
+	if d.lock == nil {
+		return fn(ctx)
+	}
+	var fnErr error
+	if err := d.lock.LockExecuteAndRelease(ctx, storeLockActionName, storeLockTimeout, func(ctx context.Context) {
+		fnErr = fn(ctx)
+	}); err != nil {
+		var lockExistsErr *serverlock.ServerLockExistsError
+		if errors.As(err, &lockExistsErr) {
+			return ErrPatternsLocked
+		}
+		return fmt.Errorf("lock execute and release: %w", err)
+	}
+	return fnErr
+}
+
+// keepCachedPatterns records a successful call to GCOM that did not replace the cached patterns, so they are marked
+// as fresh and RefreshIfStale does not call GCOM again until the next refresh is due.
+func (d *Dynamic) keepCachedPatterns(ctx context.Context) error {
+	if err := d.store.SetLastUpdated(ctx); err != nil {
+		return fmt.Errorf("store set last updated: %w", err)
+	}
 	d.mux.Lock()
-	defer d.mux.Unlock()
+	d.lastSuccess = time.Now()
+	d.mux.Unlock()
+	d.client.Metrics.LastSuccess.SetToCurrentTime()
+	return nil
+}
 
+// cacheValidators returns the HTTP cache validators that should be used to fetch the patterns.
+// It returns empty validators if there are no cached patterns, so the patterns are always fetched.
+func (d *Dynamic) cacheValidators(ctx context.Context) (angularpatternsstore.CacheValidators, error) {
+	_, ok, err := d.store.Get(ctx)
+	if err != nil {
+		return angularpatternsstore.CacheValidators{}, fmt.Errorf("store get: %w", err)
+	}
+	if !ok {
+		return angularpatternsstore.CacheValidators{}, nil
+	}
+	return d.store.GetCacheValidators(ctx)
+}
+
+// isPinned returns true if a patterns version is pinned and the provided patterns should not replace it.
+// If the provided patterns are different from both the pinned version and the version that was the latest when
+// the pin was created, and they are not in the stored versions history (older or replayed versions), a newer
+// version has been published: the pin is released and false is returned.
+func (d *Dynamic) isPinned(ctx context.Context, patterns GCOMPatterns) (bool, error) {
+	pin, ok, err := d.store.GetPin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get pin: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	b, err := json.Marshal(patterns)
+	if err != nil {
+		return false, fmt.Errorf("json marshal: %w", err)
+	}
+	hash := angularpatternsstore.PatternsHash(b)
+	if hash == pin.Hash || hash == pin.ReplacedHash {
+		return true, nil
+	}
+	versions, err := d.store.GetVersions(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get versions: %w", err)
+	}
+	for _, v := range versions {
+		if v.Hash == hash {
+			d.log.Debug("Angular patterns version has already been stored, keeping pin", "pinnedHash", pin.Hash, "hash", hash)
+			return true, nil
+		}
+	}
+	d.log.Info("Newer angular patterns version available, releasing pin", "pinnedHash", pin.Hash, "newHash", hash)
+	if err := d.store.DeletePin(ctx); err != nil {
+		return false, fmt.Errorf("delete pin: %w", err)
+	}
+	return false, nil
+}
+
+// setDetectorsFromCache sets the in-memory detectors from the patterns in the store.
+func (d *Dynamic) setDetectorsFromCache(ctx context.Context) error {
 	var cachedPatterns GCOMPatterns
 	rawCached, ok, err := d.store.Get(ctx)
 	if !ok {
@@ -171,14 +466,251 @@ func (d *Dynamic) setDetectorsFromCache(ctx context.Context) error {
 	if err := json.Unmarshal([]byte(rawCached), &cachedPatterns); err != nil {
 		return fmt.Errorf("json unmarshal: %w", err)
 	}
-	cachedDetectors, err := d.patternsToDetectors(cachedPatterns)
+	cachedDetectors, skipped, err := d.patternsToDetectors(cachedPatterns)
 	if err != nil {
 		return fmt.Errorf("convert to detectors: %w", err)
 	}
+	fetchedAt, err := d.cachedFetchedAt(ctx, angularpatternsstore.PatternsHash([]byte(rawCached)))
+	if err != nil {
+		return fmt.Errorf("cached fetched at: %w", err)
+	}
+	lastUpdated, err := d.store.GetLastUpdated(ctx)
+	if err != nil {
+		return fmt.Errorf("get last updated: %w", err)
+	}
+	schemaVersion, err := d.store.GetSchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+	cacheSource := CacheSourceDatabase
+	if d.cfg.AngularDetection.CacheBackend == setting.AngularPatternsCacheBackendRemoteCache {
+		cacheSource = CacheSourceRemoteCache
+	}
+	provenance := d.newProvenance([]byte(rawCached), fetchedAt, schemaVersion)
+	namedDetectors, namedDetectorsPatterns := compilePatterns(d.log, cachedPatterns)
+	d.mux.Lock()
 	d.detectors = cachedDetectors
+	d.patterns = cachedPatterns
+	d.namedDetectors = namedDetectors
+	d.namedDetectorsPatterns = namedDetectorsPatterns
+	d.skipped = skipped
+	d.provenance = provenance
+	d.lastSuccess = lastUpdated
+	d.cacheSource = cacheSource
+	d.mux.Unlock()
+	d.metrics.patternsLoaded.Set(float64(len(cachedDetectors)))
 	return nil
 }
 
+// cachedFetchedAt returns the time when the stored patterns version with the provided hash has been fetched.
+// If the version is the latest one (or it cannot be found), it returns the latest update time of the store.
+// Otherwise (the patterns have been rolled back), it returns the time when the version has been stored.
+func (d *Dynamic) cachedFetchedAt(ctx context.Context, hash string) (time.Time, error) {
+	versions, err := d.store.GetVersions(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get versions: %w", err)
+	}
+	for i, v := range versions {
+		if v.Hash == hash && i > 0 {
+			return v.CreatedAt, nil
+		}
+	}
+	return d.store.GetLastUpdated(ctx)
+}
+
+// scheduledUpdate updates the detectors from the background service.
+// If the patterns are cached in the remote cache, only the instance that acquires the lock fetches them from GCOM,
+// while the other instances read the patterns fetched by that instance from the remote cache.
+func (d *Dynamic) scheduledUpdate(ctx context.Context) error {
+	d.mux.Lock()
+	d.lastAttempt = time.Now()
+	d.mux.Unlock()
+	if d.lock == nil {
+		return d.updateDetectors(ctx)
+	}
+	var fetched bool
+	var updateErr error
+	if err := d.lock.LockAndExecute(ctx, refreshLockActionName, d.refreshLockInterval(), func(ctx context.Context) {
+		fetched = true
+		updateErr = d.updateDetectors(ctx)
+	}); err != nil {
+		return fmt.Errorf("lock and execute: %w", err)
+	}
+	if fetched {
+		return updateErr
+	}
+	d.log.Debug("Angular patterns fetched by another instance, reading them from the remote cache")
+	return d.reloadFromCache(ctx)
+}
+
+// reloadFromCache sets the in-memory detectors from the patterns in the store, if they are different from the
+// cached ones, and notifies the subscribers.
+func (d *Dynamic) reloadFromCache(ctx context.Context) error {
+	d.updateMux.Lock()
+	defer d.updateMux.Unlock()
+
+	rawCached, ok, err := d.store.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("store get: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	if angularpatternsstore.PatternsHash([]byte(rawCached)) == d.Provenance().Hash {
+		// Same patterns, only keep track of the last update made by the other instance
+		lastUpdated, err := d.store.GetLastUpdated(ctx)
+		if err != nil {
+			return fmt.Errorf("get last updated: %w", err)
+		}
+		d.mux.Lock()
+		if lastUpdated.After(d.lastSuccess) {
+			d.lastSuccess = lastUpdated
+		}
+		d.mux.Unlock()
+		return nil
+	}
+	if err := d.setDetectorsFromCache(ctx); err != nil {
+		return fmt.Errorf("set detectors from cache: %w", err)
+	}
+	d.subscribers.publish(DetectorsUpdated{Provider: ProviderDynamic, UpdatedAt: time.Now()})
+	return nil
+}
+
+// refreshLockInterval returns the minimum interval between two fetches from GCOM across all the instances sharing
+// the remote cache. It's slightly shorter than the refresh interval, so the instance that fetched the patterns last
+// time can acquire the lock again on its next run.
+func (d *Dynamic) refreshLockInterval() time.Duration {
+	return d.refreshInterval() * 9 / 10
+}
+
+// refreshInterval returns the interval that passes between background job runs.
+func (d *Dynamic) refreshInterval() time.Duration {
+	if d.cfg.AngularDetection.RefreshInterval > 0 {
+		return d.cfg.AngularDetection.RefreshInterval
+	}
+	return backgroundJobInterval
+}
+
+// nextRefreshInterval returns the refresh interval plus a random jitter between 0 and the configured refresh
+// jitter, so that multiple Grafana instances do not call GCOM at the same time.
+func (d *Dynamic) nextRefreshInterval() time.Duration {
+	return remoterules.NextInterval(d.refreshInterval(), d.cfg.AngularDetection.RefreshJitter)
+}
+
+// RefreshIfStale synchronously updates the detectors if the cached patterns are older than the refresh interval.
+// The update goes through the same lock as the background service, so only one instance calls GCOM.
+// It does nothing if the dynamic angular detection patterns are disabled, or if this instance has already tried to
+// update them during the last refresh interval, so failing fetches do not slow down every caller.
+func (d *Dynamic) RefreshIfStale(ctx context.Context) error {
+	if d.IsDisabled() {
+		return nil
+	}
+	lastUpdate, err := d.store.GetLastUpdated(ctx)
+	if err != nil {
+		return fmt.Errorf("get last updated: %w", err)
+	}
+	if time.Since(lastUpdate) < d.refreshInterval() {
+		return nil
+	}
+	d.mux.Lock()
+	lastAttempt := d.lastAttempt
+	recentAttempt := time.Since(lastAttempt) < d.refreshInterval()
+	if !recentAttempt {
+		// Record the attempt right away, so concurrent callers do not update the patterns too
+		d.lastAttempt = time.Now()
+	}
+	d.mux.Unlock()
+	if recentAttempt {
+		d.log.Debug("Cached patterns are stale, but an update has been attempted recently", "lastUpdated", lastUpdate, "lastAttempt", lastAttempt)
+		return nil
+	}
+	d.log.Debug("Cached patterns are stale, updating patterns", "lastUpdated", lastUpdate)
+	return d.scheduledUpdate(ctx)
+}
+
+// Refresh synchronously fetches the patterns from GCOM and updates the detectors.
+func (d *Dynamic) Refresh(ctx context.Context) error {
+	return d.updateDetectors(ctx)
+}
+
+// Patterns returns the cached patterns, alongside their provenance.
+func (d *Dynamic) Patterns() (GCOMPatterns, Provenance) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.patterns, d.provenance
+}
+
+// LastUpdated returns the time when the cached patterns have been last updated.
+func (d *Dynamic) LastUpdated(ctx context.Context) (time.Time, error) {
+	return d.store.GetLastUpdated(ctx)
+}
+
+// Versions returns the stored versions of the patterns, the most recent one first.
+func (d *Dynamic) Versions(ctx context.Context) ([]angularpatternsstore.PatternsVersion, error) {
+	return d.store.GetVersions(ctx)
+}
+
+// History returns the audit history of the stored patterns, the most recent entry first.
+func (d *Dynamic) History(ctx context.Context) ([]angularpatternsstore.HistoryEntry, error) {
+	return d.store.GetHistory(ctx)
+}
+
+// Pin returns the pinned patterns version, if any.
+func (d *Dynamic) Pin(ctx context.Context) (angularpatternsstore.Pin, bool, error) {
+	return d.store.GetPin(ctx)
+}
+
+// Rollback restores the stored patterns version with the provided hash and pins it, so it is not replaced by
+// the background service until a newer version is published on GCOM or the pin is released.
+// If there's no such version, it returns angularpatternsstore.ErrVersionNotFound.
+func (d *Dynamic) Rollback(ctx context.Context, hash string) error {
+	return d.withStoreLock(ctx, func(ctx context.Context) error {
+		return d.rollback(ctx, hash)
+	})
+}
+
+// rollback implements Rollback. It must be called with the store lock held.
+func (d *Dynamic) rollback(ctx context.Context, hash string) error {
+	versions, err := d.store.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("get versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return angularpatternsstore.ErrVersionNotFound
+	}
+	if err := d.store.Rollback(ctx, hash); err != nil {
+		return fmt.Errorf("store rollback: %w", err)
+	}
+	if err := d.store.SetPin(ctx, angularpatternsstore.Pin{
+		Hash:         hash,
+		ReplacedHash: versions[0].Hash,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return fmt.Errorf("store set pin: %w", err)
+	}
+	if err := d.setDetectorsFromCache(ctx); err != nil {
+		return fmt.Errorf("set detectors from cache: %w", err)
+	}
+	d.subscribers.publish(DetectorsUpdated{Provider: ProviderDynamic, UpdatedAt: time.Now()})
+	d.log.Info("Rolled back angular patterns", "hash", hash)
+	return nil
+}
+
+// ReleasePin releases the pinned patterns version, if any.
+// The latest patterns from GCOM will be used again starting from the next update.
+func (d *Dynamic) ReleasePin(ctx context.Context) error {
+	return d.withStoreLock(ctx, func(ctx context.Context) error {
+		if err := d.store.DeletePin(ctx); err != nil {
+			return fmt.Errorf("store delete pin: %w", err)
+		}
+		// The cached patterns may not be the latest ones anymore, so make sure they are fully re-downloaded
+		if err := d.store.SetCacheValidators(ctx, angularpatternsstore.CacheValidators{}); err != nil {
+			return fmt.Errorf("store set cache validators: %w", err)
+		}
+		return nil
+	})
+}
+
 // IsDisabled returns true if FlagPluginsDynamicAngularDetectionPatterns is not enabled.
 func (d *Dynamic) IsDisabled() bool {
 	return !d.features.IsEnabled(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns)
@@ -188,74 +720,192 @@ func (d *Dynamic) IsDisabled() bool {
 func (d *Dynamic) Run(ctx context.Context) error {
 	d.log.Debug("Started background service")
 
-	// Determine when next run is, and check if we should run immediately
+	// Determine when next run is, the first run happens immediately if the patterns are already stale
 	lastUpdate, err := d.store.GetLastUpdated(ctx)
 	if err != nil {
 		return fmt.Errorf("get last updated: %w", err)
 	}
-	nextRunUntil := time.Until(lastUpdate.Add(backgroundJobInterval))
+	return remoterules.RunPeriodically(ctx, lastUpdate, d.nextRefreshInterval, func() {
+		st := time.Now()
+		d.log.Debug("Updating patterns")
 
-	ticker := time.NewTicker(backgroundJobInterval)
-	defer ticker.Stop()
-
-	var tick <-chan time.Time
-	if nextRunUntil <= 0 {
-		// Do first run immediately
-		firstTick := make(chan time.Time, 1)
-		tick = firstTick
-
-		firstTick <- time.Now()
-	} else {
-		// Do first run after a certain amount of time
-		ticker.Reset(nextRunUntil)
-		tick = ticker.C
-	}
-
-	// Keep running periodically
-	for {
-		select {
-		case <-tick:
-			st := time.Now()
-			d.log.Debug("Updating patterns")
-
-			if err := d.updateDetectors(context.Background()); err != nil {
-				d.log.Error("Error while updating detectors", "error", err)
-			}
-			d.log.Info("Patterns update finished", "duration", time.Since(st))
-
-			// Restore default ticker if we run with a shorter interval the first time
-			ticker.Reset(backgroundJobInterval)
-			tick = ticker.C
-		case <-ctx.Done():
-			return ctx.Err()
+		if err := d.scheduledUpdate(context.Background()); err != nil {
+			d.log.Error("Error while updating detectors", "error", err)
 		}
-	}
+		d.checkStale()
+		d.log.Info("Patterns update finished", "duration", time.Since(st))
+	})
 }
 
 // ProvideDetectors returns the cached detectors. It returns an empty slice if there's no value.
-func (d *Dynamic) ProvideDetectors(_ context.Context) []angulardetector.AngularDetector {
+// If the cached detectors are stale, the static detectors are appended to them.
+func (d *Dynamic) ProvideDetectors(ctx context.Context) []angulardetector.AngularDetector {
 	d.mux.RLock()
 	r := d.detectors
 	d.mux.RUnlock()
+	if len(r) == 0 || !d.checkStale() {
+		return r
+	}
+	static := d.static.ProvideDetectors(ctx)
+	merged := make([]angulardetector.AngularDetector, 0, len(r)+len(static))
+	return append(append(merged, r...), static...)
+}
+
+// ProvideNamedDetectors returns the cached detectors, named after the patterns they have been created from.
+// If the cached detectors are stale, they are merged with the static detectors, which have a lower precedence.
+func (d *Dynamic) ProvideNamedDetectors(ctx context.Context) []angulardetector.NamedDetector {
+	d.mux.RLock()
+	r := d.namedDetectors
+	d.mux.RUnlock()
+	if len(r) == 0 || !d.checkStale() {
+		return r
+	}
+	return angulardetector.ChainDetectorsProvider{namedDetectorsProvider(r), d.static}.ProvideNamedDetectors(ctx)
+}
+
+// namedDetectorsProvider is an angulardetector.NamedDetectorsProvider that returns a fixed slice of NamedDetector.
+type namedDetectorsProvider []angulardetector.NamedDetector
+
+func (p namedDetectorsProvider) ProvideDetectors(_ context.Context) []angulardetector.AngularDetector {
+	r := make([]angulardetector.AngularDetector, 0, len(p))
+	for _, d := range p {
+		r = append(r, d.Detector)
+	}
 	return r
 }
 
-// Same configuration as pkg/plugins/repo/client.go
-func makeHttpClient() http.Client {
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+func (p namedDetectorsProvider) ProvideNamedDetectors(_ context.Context) []angulardetector.NamedDetector {
+	return p
+}
+
+// IsStale returns true if the cached patterns have not been confirmed by GCOM for longer than the configured max
+// age. It always returns false if no max age is configured or the cache has not been populated yet.
+func (d *Dynamic) IsStale() bool {
+	maxAge := d.cfg.AngularDetection.PatternsMaxAge
+	if maxAge <= 0 {
+		return false
 	}
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.cacheSource != CacheSourceNone && time.Since(d.lastSuccess) > maxAge
+}
+
+// checkStale returns IsStale(), and logs a warning and updates the metrics when the staleness changes.
+func (d *Dynamic) checkStale() bool {
+	stale := d.IsStale()
+	if d.stale.CompareAndSwap(!stale, stale) {
+		if stale {
+			d.log.Warn(
+				"Cached angular patterns are stale, merging in the static patterns",
+				"maxAge", d.cfg.AngularDetection.PatternsMaxAge, "lastSuccess", d.LastSuccess(),
+			)
+			d.metrics.stale.Set(1)
+		} else {
+			d.log.Info("Cached angular patterns are not stale anymore")
+			d.metrics.stale.Set(0)
+		}
+	}
+	return stale
+}
 
-	return http.Client{
-		Timeout:   10 * time.Second,
-		Transport: tr,
+// LastSuccess returns the time when the cached patterns have been last confirmed by GCOM.
+// It returns the zero time if the cache has not been populated yet.
+func (d *Dynamic) LastSuccess() time.Time {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.lastSuccess
+}
+
+// Subscribe returns a channel that receives a DetectorsUpdated event every time the cached detectors change,
+// either because new patterns have been fetched from GCOM or because the patterns have been rolled back.
+// The channel is closed when the provided context is done.
+func (d *Dynamic) Subscribe(ctx context.Context) <-chan DetectorsUpdated {
+	return d.subscribers.subscribe(ctx)
+}
+
+// CacheSource returns the source the cached detectors have been populated from.
+// It returns CacheSourceNone if the cache has not been populated yet.
+func (d *Dynamic) CacheSource() CacheSource {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.cacheSource
+}
+
+// IsReady returns true if the cached detectors have been populated, either from the database or from GCOM.
+// It always returns true if the dynamic angular detection patterns are disabled, as the static detectors are used.
+func (d *Dynamic) IsReady() bool {
+	return d.IsDisabled() || d.CacheSource() != CacheSourceNone
+}
+
+// CircuitBreakerState returns the state of the circuit breaker that protects GCOM from repeated failing fetches.
+func (d *Dynamic) CircuitBreakerState() remoterules.CircuitBreakerState {
+	return d.client.Breaker.State()
+}
+
+// Status is the status of the background refresh of the dynamic angular detection patterns.
+type Status struct {
+	// CacheSource is the source the cached detectors have been populated from.
+	CacheSource CacheSource
+
+	// LastSuccess is the time when the cached patterns have been last confirmed by GCOM.
+	LastSuccess time.Time
+
+	// ConsecutiveFailures is the number of consecutive failed updates since the last successful one.
+	ConsecutiveFailures int
+
+	// CircuitBreakerState is the state of the circuit breaker that protects GCOM from repeated failing fetches.
+	CircuitBreakerState remoterules.CircuitBreakerState
+
+	// Stale is true if the cached patterns are older than the configured max age.
+	Stale bool
+}
+
+// Healthy returns true if the latest update succeeded, so the cached patterns are being refreshed.
+func (s Status) Healthy() bool {
+	return s.ConsecutiveFailures == 0 && s.CircuitBreakerState == remoterules.CircuitBreakerClosed
+}
+
+// Status returns the status of the background refresh of the patterns.
+func (d *Dynamic) Status() Status {
+	return Status{
+		CacheSource:         d.CacheSource(),
+		LastSuccess:         d.LastSuccess(),
+		ConsecutiveFailures: d.client.Breaker.ConsecutiveFailures(),
+		CircuitBreakerState: d.CircuitBreakerState(),
+		Stale:               d.IsStale(),
+	}
+}
+
+// SchemaStatus returns information about the schema of the latest patterns fetched from GCOM, such as the number
+// of patterns skipped because of version skew.
+func (d *Dynamic) SchemaStatus() SchemaStatus {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.schemaStatus
+}
+
+// Provenance returns the provenance of the cached patterns.
+func (d *Dynamic) Provenance() Provenance {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return d.provenance
+}
+
+// MatchingPatterns returns the cached patterns that match the provided module.js content, alongside their provenance.
+// Patterns that cannot be converted to detectors are ignored.
+func (d *Dynamic) MatchingPatterns(moduleJs []byte) (GCOMPatterns, Provenance) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	var r GCOMPatterns
+	f := angulardetector.NewJSFile(moduleJs)
+	for i, nd := range d.namedDetectors {
+		if nd.Suppressed {
+			continue
+		}
+		if angulardetector.DetectAngularFile(nd.Detector, f) {
+			r = append(r, d.namedDetectorsPatterns[i])
+		}
 	}
+	return r, d.provenance
 }
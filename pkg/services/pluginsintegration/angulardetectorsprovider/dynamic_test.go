@@ -1,23 +1,36 @@
 package angulardetectorsprovider
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/remoterules"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestDynamicAngularDetectorsProvider(t *testing.T) {
@@ -27,18 +40,19 @@ func TestDynamicAngularDetectorsProvider(t *testing.T) {
 	t.Cleanup(srv.Close)
 
 	svc := provideDynamic(t, srv.URL)
-	mockGCOMDetectors, err := svc.patternsToDetectors(mockGCOMPatterns)
+	mockGCOMDetectors, _, err := svc.patternsToDetectors(mockGCOMPatterns)
 	require.NoError(t, err)
 
 	t.Run("patternsToDetectors", func(t *testing.T) {
 		t.Run("valid", func(t *testing.T) {
-			d, err := svc.patternsToDetectors(mockGCOMPatterns)
+			d, skipped, err := svc.patternsToDetectors(mockGCOMPatterns)
 			require.NoError(t, err)
+			require.Zero(t, skipped)
 			checkMockDetectorsSlice(t, d)
 		})
 
 		t.Run("invalid regex", func(t *testing.T) {
-			_, err := svc.patternsToDetectors(GCOMPatterns{GCOMPattern{Name: "invalid", Type: GCOMPatternTypeRegex, Pattern: `[`}})
+			_, _, err := svc.patternsToDetectors(GCOMPatterns{GCOMPattern{Name: "invalid", Type: GCOMPatternTypeRegex, Pattern: `[`}})
 			require.Error(t, err)
 		})
 
@@ -51,8 +65,9 @@ func TestDynamicAngularDetectorsProvider(t *testing.T) {
 			newPatterns = append(newPatterns, GCOMPattern{Name: "Unknown", Pattern: "Unknown", Type: "Unknown"})
 
 			// Convert patterns to detector and the unknown one should be silently ignored
-			detectors, err := svc.patternsToDetectors(newPatterns)
+			detectors, skipped, err := svc.patternsToDetectors(newPatterns)
 			require.NoError(t, err)
+			require.Equal(t, 1, skipped)
 			checkMockDetectorsSlice(t, detectors)
 		})
 	})
@@ -92,18 +107,30 @@ func TestDynamicAngularDetectorsProvider(t *testing.T) {
 
 	t.Run("fetch", func(t *testing.T) {
 		t.Run("returns value from gcom api", func(t *testing.T) {
-			r, err := svc.fetch(context.Background())
+			r, _, err := svc.fetch(context.Background(), angularpatternsstore.CacheValidators{})
 			require.NoError(t, err)
 
 			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should be called")
-			require.Equal(t, mockGCOMPatterns, r)
+			require.Equal(t, 1, r.SchemaVersion)
+			require.Equal(t, mockGCOMPatterns, r.Patterns)
+		})
+
+		t.Run("parses versioned response", func(t *testing.T) {
+			scenario := newSchemaVersionGCOMScenario(t, 2, mockGCOMPatterns)
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+
+			r, _, err := provideDynamic(t, srv.URL).fetch(context.Background(), angularpatternsstore.CacheValidators{})
+			require.NoError(t, err)
+			require.Equal(t, 2, r.SchemaVersion)
+			require.Equal(t, mockGCOMPatterns, r.Patterns)
 		})
 
 		t.Run("handles timeout", func(t *testing.T) {
 			// ctx that expired in the past
 			ctx, canc := context.WithDeadline(context.Background(), time.Now().Add(time.Second*-30))
 			defer canc()
-			_, err := svc.fetch(ctx)
+			_, _, err := svc.fetch(ctx, angularpatternsstore.CacheValidators{})
 			require.ErrorIs(t, err, context.DeadlineExceeded)
 			require.False(t, gcom.httpCalls.called(), "gcom api should not be called")
 			require.Empty(t, svc.ProvideDetectors(context.Background()))
@@ -111,6 +138,44 @@ func TestDynamicAngularDetectorsProvider(t *testing.T) {
 	})
 
 	t.Run("updateDetectors", func(t *testing.T) {
+		t.Run("cached detectors can be read while fetching", func(t *testing.T) {
+			fetching := make(chan struct{})
+			release := make(chan struct{})
+			slowSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				close(fetching)
+				<-release
+				mockGCOMHTTPHandlerFunc(w, req)
+			}))
+			t.Cleanup(slowSrv.Close)
+
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns[:1]))
+			svc := provideDynamic(t, slowSrv.URL, provideDynamicOpts{store: store})
+
+			done := make(chan error)
+			go func() {
+				done <- svc.updateDetectors(context.Background())
+			}()
+			<-fetching
+			read := make(chan struct{})
+			go func() {
+				svc.ProvideDetectors(context.Background())
+				svc.ProvideNamedDetectors(context.Background())
+				svc.Patterns()
+				close(read)
+			}()
+			select {
+			case <-read:
+			case <-time.After(time.Second * 5):
+				t.Fatal("cached detectors should be readable while fetching")
+			}
+			require.Len(t, svc.ProvideDetectors(context.Background()), 1)
+
+			close(release)
+			require.NoError(t, <-done)
+			checkMockDetectors(t, svc)
+		})
+
 		t.Run("successful", func(t *testing.T) {
 			svc := provideDynamic(t, srv.URL)
 
@@ -183,6 +248,936 @@ func TestDynamicAngularDetectorsProvider(t *testing.T) {
 		})
 	})
 
+	t.Run("metrics", func(t *testing.T) {
+		t.Run("successful update", func(t *testing.T) {
+			patterns := append(newMockGCOMPatterns(), GCOMPattern{Name: "unknown", Type: "unknown"})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				require.NoError(t, json.NewEncoder(w).Encode(patterns))
+			}))
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+
+			st := time.Now()
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.Equal(t, 1, testutil.CollectAndCount(svc.client.Metrics.FetchDuration))
+			require.Zero(t, testutil.ToFloat64(svc.client.Metrics.FetchErrors))
+			require.Equal(t, float64(1), testutil.ToFloat64(svc.client.Metrics.HTTPResponses.WithLabelValues("200")))
+			require.Equal(t, float64(2), testutil.ToFloat64(svc.metrics.patternsLoaded))
+			require.Equal(t, float64(1), testutil.ToFloat64(svc.metrics.unknownTypesSkipped))
+			require.GreaterOrEqual(t, testutil.ToFloat64(svc.client.Metrics.LastSuccess), float64(st.Unix()))
+		})
+
+		t.Run("failed update", func(t *testing.T) {
+			srv := newError500GCOMScenario().newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+
+			require.Error(t, svc.updateDetectors(context.Background()))
+			require.Equal(t, float64(1), testutil.ToFloat64(svc.client.Metrics.FetchErrors))
+			require.Equal(t, float64(1), testutil.ToFloat64(svc.client.Metrics.HTTPResponses.WithLabelValues("500")))
+			require.Zero(t, testutil.ToFloat64(svc.metrics.patternsLoaded))
+			require.Zero(t, testutil.ToFloat64(svc.client.Metrics.LastSuccess))
+		})
+	})
+
+	t.Run("updateDetectors conditional request", func(t *testing.T) {
+		const etag = `"v1"`
+		const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+		gcom := &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("If-None-Match") == etag && req.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			mockGCOMHTTPHandlerFunc(w, req)
+		}}
+		srv := gcom.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+
+		t.Run("stores validators", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			validators, err := svc.store.GetCacheValidators(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, angularpatternsstore.CacheValidators{ETag: etag, LastModified: lastModified}, validators)
+		})
+
+		t.Run("not modified skips store write and detectors rebuild", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			// The cached patterns are different from the ones returned by GCOM, so we can detect an update
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns[:1]))
+			require.NoError(t, store.SetCacheValidators(context.Background(), angularpatternsstore.CacheValidators{
+				ETag:         etag,
+				LastModified: lastModified,
+			}))
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+			detectors := svc.ProvideDetectors(context.Background())
+
+			st := time.Now().Truncate(time.Second)
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.True(t, gcom.httpCalls.called(), "gcom api should be called")
+			require.Equal(t, detectors, svc.ProvideDetectors(context.Background()))
+			rawPatterns, _, err := store.Get(context.Background())
+			require.NoError(t, err)
+			var patterns GCOMPatterns
+			require.NoError(t, json.Unmarshal([]byte(rawPatterns), &patterns))
+			require.Equal(t, mockGCOMPatterns[:1], patterns)
+
+			lastUpdated, err := store.GetLastUpdated(context.Background())
+			require.NoError(t, err)
+			require.False(t, lastUpdated.Before(st), "last updated should be touched")
+		})
+
+		t.Run("no validators are sent if there are no cached patterns", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.SetCacheValidators(context.Background(), angularpatternsstore.CacheValidators{
+				ETag:         etag,
+				LastModified: lastModified,
+			}))
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			checkMockDetectors(t, svc)
+		})
+	})
+
+	t.Run("updateDetectors signature verification", func(t *testing.T) {
+		entity, publicKey := newTestPGPKey(t)
+		otherEntity, _ := newTestPGPKey(t)
+
+		for _, tc := range []struct {
+			name      string
+			required  bool
+			signer    *openpgp.Entity
+			expUpdate bool
+		}{
+			{name: "valid signature", required: true, signer: entity, expUpdate: true},
+			{name: "invalid signature", required: true, signer: otherEntity, expUpdate: false},
+			{name: "missing signature", required: true, signer: nil, expUpdate: false},
+			{name: "verification not required", required: false, signer: nil, expUpdate: true},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				gcom := &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+					if req.URL.Path != gcomAngularPatternsSignaturePath {
+						mockGCOMHTTPHandlerFunc(w, req)
+						return
+					}
+					if tc.signer == nil {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					require.NoError(t, openpgp.ArmoredDetachSign(w, tc.signer, bytes.NewReader(mockGCOMResponse), nil))
+				}}
+				srv := gcom.newHTTPTestServer()
+				t.Cleanup(srv.Close)
+
+				svc := provideDynamic(t, srv.URL, provideDynamicOpts{
+					angularDetection: setting.AngularDetectionSettings{RequirePatternsSignature: tc.required},
+				})
+				svc.client.PublicKey = publicKey
+
+				err := svc.updateDetectors(context.Background())
+				if !tc.expUpdate {
+					require.Error(t, err)
+					require.Empty(t, svc.ProvideDetectors(context.Background()))
+					_, ok, err := svc.store.Get(context.Background())
+					require.NoError(t, err)
+					require.False(t, ok, "patterns should not be stored")
+					return
+				}
+				require.NoError(t, err)
+				checkMockDetectors(t, svc)
+			})
+		}
+	})
+
+	t.Run("updateDetectors empty response", func(t *testing.T) {
+		scenario := newEmptyGCOMScenario()
+		srv := scenario.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+
+		for _, tc := range []struct {
+			name   string
+			policy setting.AngularEmptyPatternsPolicy
+			exp    func(t *testing.T, svc *Dynamic)
+		}{
+			{
+				name:   "keeps previous patterns by default",
+				policy: "",
+				exp:    checkMockDetectors,
+			},
+			{
+				name:   "keeps previous patterns with keep policy",
+				policy: setting.AngularEmptyPatternsPolicyKeep,
+				exp:    checkMockDetectors,
+			},
+			{
+				name:   "clears patterns with clear policy",
+				policy: setting.AngularEmptyPatternsPolicyClear,
+				exp: func(t *testing.T, svc *Dynamic) {
+					require.Empty(t, svc.ProvideDetectors(context.Background()))
+					dbV, ok, err := svc.store.Get(context.Background())
+					require.NoError(t, err)
+					require.True(t, ok)
+					require.Equal(t, "[]", dbV)
+				},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+				require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+				svc := provideDynamic(t, srv.URL, provideDynamicOpts{
+					store:            store,
+					angularDetection: setting.AngularDetectionSettings{EmptyPatternsPolicy: tc.policy},
+				})
+				checkMockDetectors(t, svc)
+
+				require.NoError(t, svc.updateDetectors(context.Background()))
+				require.True(t, scenario.httpCalls.called(), "gcom api should be called")
+				tc.exp(t, svc)
+			})
+		}
+	})
+
+	t.Run("updateDetectors empty response with keep policy records the attempt", func(t *testing.T) {
+		scenario := newEmptyGCOMScenario()
+		srv := scenario.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+
+		store := &setLastUpdatedCounterStore{Service: angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())}
+		require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+		svc := provideDynamic(t, srv.URL, provideDynamicOpts{
+			store:            store,
+			angularDetection: setting.AngularDetectionSettings{EmptyPatternsPolicy: setting.AngularEmptyPatternsPolicyKeep},
+		})
+
+		require.NoError(t, svc.updateDetectors(context.Background()))
+		require.True(t, scenario.httpCalls.calledOnce(), "gcom api should be called")
+		require.True(t, store.calls.calledOnce(), "last updated should be set, so RefreshIfStale does not call gcom again")
+		checkMockDetectors(t, svc)
+	})
+
+	t.Run("updateDetectors schema version skew", func(t *testing.T) {
+		unknownPatterns := append(newMockGCOMPatterns(), GCOMPattern{Name: "Unknown", Pattern: "Unknown", Type: "Unknown"})
+
+		for _, tc := range []struct {
+			name          string
+			schemaVersion int
+			patterns      GCOMPatterns
+			expSkipped    int
+		}{
+			{name: "unknown pattern types", schemaVersion: 1, patterns: unknownPatterns, expSkipped: 1},
+			{name: "newer schema version", schemaVersion: gcomPatternsSchemaVersion + 1, patterns: newMockGCOMPatterns()[:1]},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				scenario := newSchemaVersionGCOMScenario(t, tc.schemaVersion, tc.patterns)
+				srv := scenario.newHTTPTestServer()
+				t.Cleanup(srv.Close)
+
+				t.Run("keeps previous fully supported patterns", func(t *testing.T) {
+					store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+					require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+					counterStore := &setLastUpdatedCounterStore{Service: store}
+					svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: counterStore})
+					checkMockDetectors(t, svc)
+					svc.lastSuccess = time.Time{}
+
+					require.NoError(t, svc.updateDetectors(context.Background()))
+					require.True(t, scenario.httpCalls.called(), "gcom api should be called")
+					require.True(t, counterStore.calls.calledOnce(), "last updated should be set, so RefreshIfStale does not call gcom again")
+					require.WithinDuration(t, time.Now(), svc.LastSuccess(), time.Minute)
+					checkMockDetectors(t, svc)
+					patterns, _ := svc.Patterns()
+					require.Equal(t, mockGCOMPatterns, patterns)
+					require.Equal(t, SchemaStatus{
+						SchemaVersion:          tc.schemaVersion,
+						SupportedSchemaVersion: gcomPatternsSchemaVersion,
+						SkippedPatterns:        tc.expSkipped,
+						KeptPrevious:           true,
+					}, svc.SchemaStatus())
+					require.Equal(t, float64(tc.expSkipped), testutil.ToFloat64(svc.metrics.schemaSkipped))
+				})
+
+				t.Run("applies patterns if there are no previous patterns", func(t *testing.T) {
+					svc := provideDynamic(t, srv.URL)
+
+					require.NoError(t, svc.updateDetectors(context.Background()))
+					patterns, provenance := svc.Patterns()
+					require.Equal(t, tc.patterns, patterns)
+					require.Equal(t, tc.schemaVersion, provenance.SchemaVersion)
+					require.Len(t, svc.ProvideDetectors(context.Background()), len(tc.patterns)-tc.expSkipped)
+					require.Equal(t, SchemaStatus{
+						SchemaVersion:          tc.schemaVersion,
+						SupportedSchemaVersion: gcomPatternsSchemaVersion,
+						SkippedPatterns:        tc.expSkipped,
+					}, svc.SchemaStatus())
+				})
+
+				t.Run("schema version is read back from the cache", func(t *testing.T) {
+					store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+					require.NoError(t, provideDynamic(t, srv.URL, provideDynamicOpts{store: store}).updateDetectors(context.Background()))
+
+					svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+					require.Equal(t, tc.schemaVersion, svc.Provenance().SchemaVersion)
+					versions, err := svc.Versions(context.Background())
+					require.NoError(t, err)
+					require.Len(t, versions, 1)
+					require.Equal(t, tc.schemaVersion, svc.VersionProvenance(versions[0]).SchemaVersion)
+				})
+
+				t.Run("rollback restores the schema version", func(t *testing.T) {
+					store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+					svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+					require.NoError(t, svc.updateDetectors(context.Background()))
+					require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+					require.NoError(t, svc.setDetectorsFromCache(context.Background()))
+					require.Equal(t, gcomPatternsSchemaVersion, svc.Provenance().SchemaVersion)
+
+					versions, err := svc.Versions(context.Background())
+					require.NoError(t, err)
+					require.Len(t, versions, 2)
+					require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+					require.Equal(t, tc.schemaVersion, svc.Provenance().SchemaVersion)
+				})
+			})
+		}
+
+		t.Run("previous patterns not fully supported are replaced", func(t *testing.T) {
+			scenario := newSchemaVersionGCOMScenario(t, 1, unknownPatterns)
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), GCOMPatterns{{Name: "Other", Pattern: "Other", Type: "Other"}}))
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			patterns, _ := svc.Patterns()
+			require.Equal(t, unknownPatterns, patterns)
+			require.False(t, svc.SchemaStatus().KeptPrevious)
+		})
+
+		t.Run("no version skew", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.Equal(t, SchemaStatus{SchemaVersion: 1, SupportedSchemaVersion: gcomPatternsSchemaVersion}, svc.SchemaStatus())
+			require.False(t, svc.SchemaStatus().HasVersionSkew())
+		})
+	})
+
+	t.Run("refresh interval", func(t *testing.T) {
+		t.Run("uses default interval if not configured", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			require.Equal(t, backgroundJobInterval, svc.refreshInterval())
+			require.Equal(t, backgroundJobInterval, svc.nextRefreshInterval())
+		})
+
+		t.Run("uses configured interval and jitter", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{
+				angularDetection: setting.AngularDetectionSettings{
+					RefreshInterval: time.Minute * 30,
+					RefreshJitter:   time.Minute * 5,
+				},
+			})
+			require.Equal(t, time.Minute*30, svc.refreshInterval())
+			for i := 0; i < 10; i++ {
+				next := svc.nextRefreshInterval()
+				require.GreaterOrEqual(t, next, time.Minute*30)
+				require.Less(t, next, time.Minute*35)
+			}
+		})
+	})
+
+	t.Run("updateDetectors does not mark the patterns as fresh if they cannot be stored", func(t *testing.T) {
+		svc := provideDynamic(t, srv.URL, provideDynamicOpts{
+			store: &failingSetPatternsStore{Service: angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())},
+		})
+
+		require.Error(t, svc.updateDetectors(context.Background()))
+		require.Zero(t, svc.LastSuccess())
+		require.Zero(t, testutil.ToFloat64(svc.client.Metrics.LastSuccess))
+	})
+
+	t.Run("RefreshIfStale", func(t *testing.T) {
+		t.Run("does not call gcom if patterns are fresh", func(t *testing.T) {
+			gcom := newDefaultGCOMScenario()
+			srv := gcom.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.store.Set(context.Background(), mockGCOMPatterns))
+
+			require.NoError(t, svc.RefreshIfStale(context.Background()))
+			require.False(t, gcom.httpCalls.called(), "gcom api should not be called")
+		})
+
+		t.Run("calls gcom if patterns are stale", func(t *testing.T) {
+			gcom := newDefaultGCOMScenario()
+			srv := gcom.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+			svc.store = &mockLastUpdatePatternsStore{
+				Service:     svc.store,
+				lastUpdated: time.Now().Add(time.Hour * -24),
+			}
+
+			require.NoError(t, svc.RefreshIfStale(context.Background()))
+			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should be called once")
+			checkMockDetectors(t, svc)
+		})
+
+		t.Run("does not call gcom again after a failed attempt", func(t *testing.T) {
+			gcom := newError500GCOMScenario()
+			srv := gcom.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+
+			require.Error(t, svc.RefreshIfStale(context.Background()))
+			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should be called once")
+
+			require.NoError(t, svc.RefreshIfStale(context.Background()))
+			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should not be called again")
+		})
+
+		t.Run("does not call gcom if another instance holds the refresh lock", func(t *testing.T) {
+			gcom := newDefaultGCOMScenario()
+			srv := gcom.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			lock := &fakeRefreshLock{}
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{lock: lock})
+
+			require.NoError(t, svc.RefreshIfStale(context.Background()))
+			require.False(t, gcom.httpCalls.called(), "gcom api should not be called")
+			require.Equal(t, svc.refreshLockInterval(), lock.maxInterval)
+		})
+
+		t.Run("does nothing if disabled", func(t *testing.T) {
+			gcom := newDefaultGCOMScenario()
+			srv := gcom.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL)
+			svc.features = featuremgmt.WithFeatures()
+
+			require.NoError(t, svc.RefreshIfStale(context.Background()))
+			require.False(t, gcom.httpCalls.called(), "gcom api should not be called")
+		})
+	})
+
+	t.Run("MatchingPatterns", func(t *testing.T) {
+		svc := provideDynamic(t, srv.URL)
+		require.NoError(t, svc.updateDetectors(context.Background()))
+
+		t.Run("returns matching patterns", func(t *testing.T) {
+			r, provenance := svc.MatchingPatterns([]byte(`define(["app/plugins/sdk"], function(sdk) { sdk.PanelCtrl })`))
+			require.Equal(t, GCOMPatterns{mockGCOMPatterns[0]}, r)
+			require.Equal(t, svc.Provenance(), provenance)
+		})
+
+		t.Run("returns empty result if nothing matches", func(t *testing.T) {
+			r, _ := svc.MatchingPatterns([]byte(`console.log("react")`))
+			require.Empty(t, r)
+		})
+
+		t.Run("detectors are compiled once", func(t *testing.T) {
+			named := svc.ProvideNamedDetectors(context.Background())
+			require.Len(t, named, len(mockGCOMPatterns))
+			for i, d := range svc.ProvideNamedDetectors(context.Background()) {
+				require.Same(t, named[i].Detector, d.Detector)
+			}
+		})
+	})
+
+	t.Run("Provenance", func(t *testing.T) {
+		expHash := angularpatternsstore.PatternsHash(mockGCOMResponseCompact(t))
+
+		t.Run("is set after update", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			st := time.Now()
+			require.NoError(t, svc.updateDetectors(context.Background()))
+
+			provenance := svc.Provenance()
+			require.Equal(t, srv.URL+gcomAngularPatternsPath, provenance.Source)
+			require.Equal(t, expHash, provenance.Hash)
+			require.Equal(t, gcomPatternsSchemaVersion, provenance.SchemaVersion)
+			require.False(t, provenance.FetchedAt.Before(st))
+		})
+
+		t.Run("is restored from cache", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+			lastUpdated, err := store.GetLastUpdated(context.Background())
+			require.NoError(t, err)
+
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+			provenance := svc.Provenance()
+			require.Equal(t, srv.URL+gcomAngularPatternsPath, provenance.Source)
+			require.Equal(t, expHash, provenance.Hash)
+			require.Equal(t, lastUpdated, provenance.FetchedAt)
+		})
+	})
+
+	t.Run("IsReady", func(t *testing.T) {
+		t.Run("not ready if cache is empty", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			require.False(t, svc.IsReady())
+			require.Equal(t, CacheSourceNone, svc.CacheSource())
+		})
+
+		t.Run("ready after update", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.True(t, svc.IsReady())
+			require.Equal(t, CacheSourceRemote, svc.CacheSource())
+		})
+
+		t.Run("ready after restore from database", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+			require.True(t, svc.IsReady())
+			require.Equal(t, CacheSourceDatabase, svc.CacheSource())
+		})
+
+		t.Run("not ready if update fails", func(t *testing.T) {
+			errSrv := newError500GCOMScenario().newHTTPTestServer()
+			t.Cleanup(errSrv.Close)
+			svc := provideDynamic(t, errSrv.URL)
+			require.Error(t, svc.updateDetectors(context.Background()))
+			require.False(t, svc.IsReady())
+		})
+
+		t.Run("always ready if disabled", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			svc.features = featuremgmt.WithFeatures()
+			require.True(t, svc.IsReady())
+		})
+	})
+
+	t.Run("stale patterns", func(t *testing.T) {
+		staticDetectors := angularinspector.NewDefaultStaticDetectorsProvider().ProvideDetectors(context.Background())
+		maxAge := provideDynamicOpts{angularDetection: setting.AngularDetectionSettings{PatternsMaxAge: time.Hour}}
+
+		setup := func(t *testing.T, gcomURL string) *Dynamic {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+			opts := maxAge
+			opts.store = store
+			svc := provideDynamic(t, gcomURL, opts)
+			svc.lastSuccess = time.Now().Add(-time.Hour * 2)
+			return svc
+		}
+
+		t.Run("not stale without max age", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{store: store})
+			svc.lastSuccess = time.Now().Add(-time.Hour * 24 * 365)
+			require.False(t, svc.IsStale())
+			require.Len(t, svc.ProvideDetectors(context.Background()), len(mockGCOMDetectors))
+		})
+
+		t.Run("not stale if cache is empty", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL, maxAge)
+			require.False(t, svc.IsStale())
+			require.Empty(t, svc.ProvideDetectors(context.Background()))
+		})
+
+		t.Run("not stale after restore from database", func(t *testing.T) {
+			store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, store.Set(context.Background(), mockGCOMPatterns))
+			opts := maxAge
+			opts.store = store
+			svc := provideDynamic(t, srv.URL, opts)
+			require.False(t, svc.IsStale())
+			require.WithinDuration(t, time.Now(), svc.LastSuccess(), time.Minute)
+		})
+
+		t.Run("merges static detectors if stale", func(t *testing.T) {
+			svc := setup(t, srv.URL)
+			require.True(t, svc.IsStale())
+
+			detectors := svc.ProvideDetectors(context.Background())
+			require.Len(t, detectors, len(mockGCOMDetectors)+len(staticDetectors))
+			require.Equal(t, mockGCOMDetectors, detectors[:len(mockGCOMDetectors)])
+			require.Equal(t, staticDetectors, detectors[len(mockGCOMDetectors):])
+			require.Equal(t, 1.0, testutil.ToFloat64(svc.metrics.stale))
+
+			// Static detectors with the same name as a cached detector are replaced by the cached one
+			// (the static PanelCtrl and QueryCtrl detectors have the same names as the GCOM ones).
+			named := svc.ProvideNamedDetectors(context.Background())
+			require.Len(t, named, len(mockGCOMPatterns)+len(staticDetectors)-2)
+			names := map[string]struct{}{}
+			for i, d := range named {
+				if i < len(mockGCOMPatterns) {
+					require.Equal(t, mockGCOMPatterns[i].Name, d.Name)
+				}
+				require.NotContains(t, names, d.Name)
+				names[d.Name] = struct{}{}
+			}
+		})
+
+		t.Run("successful update clears staleness", func(t *testing.T) {
+			svc := setup(t, srv.URL)
+			require.True(t, svc.IsStale())
+			svc.ProvideDetectors(context.Background())
+			require.Equal(t, 1.0, testutil.ToFloat64(svc.metrics.stale))
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.False(t, svc.IsStale())
+			require.Len(t, svc.ProvideDetectors(context.Background()), len(mockGCOMDetectors))
+			require.Equal(t, 0.0, testutil.ToFloat64(svc.metrics.stale))
+		})
+
+		t.Run("failed update keeps staleness", func(t *testing.T) {
+			errSrv := newError500GCOMScenario().newHTTPTestServer()
+			t.Cleanup(errSrv.Close)
+			svc := setup(t, errSrv.URL)
+			require.Error(t, svc.updateDetectors(context.Background()))
+			require.True(t, svc.IsStale())
+		})
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		oldPatterns := mockGCOMPatterns[:1]
+		newPatterns := GCOMPatterns{{Name: "newer", Type: GCOMPatternTypeContains, Pattern: "Newer"}}
+
+		setup := func(t *testing.T) (*Dynamic, string) {
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.store.Set(context.Background(), oldPatterns))
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			checkMockDetectors(t, svc)
+
+			versions, err := svc.Versions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+			return svc, versions[1].Hash
+		}
+
+		t.Run("restores and pins the version", func(t *testing.T) {
+			svc, oldHash := setup(t)
+			require.NoError(t, svc.Rollback(context.Background(), oldHash))
+			require.Len(t, svc.ProvideDetectors(context.Background()), 1)
+
+			pin, ok, err := svc.Pin(context.Background())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, oldHash, pin.Hash)
+
+			// Same patterns from GCOM do not replace the pinned version
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.Len(t, svc.ProvideDetectors(context.Background()), 1)
+			_, ok, err = svc.Pin(context.Background())
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+
+		t.Run("newer version releases the pin", func(t *testing.T) {
+			svc, oldHash := setup(t)
+			require.NoError(t, svc.Rollback(context.Background(), oldHash))
+
+			newerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				require.NoError(t, json.NewEncoder(w).Encode(newPatterns))
+			}))
+			t.Cleanup(newerSrv.Close)
+			svc.client.BaseURL = newerSrv.URL
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.Equal(t, newPatterns, svc.patterns)
+			_, ok, err := svc.Pin(context.Background())
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+
+		t.Run("older version does not release the pin", func(t *testing.T) {
+			oldestPatterns := GCOMPatterns{{Name: "oldest", Type: GCOMPatternTypeContains, Pattern: "Oldest"}}
+			svc := provideDynamic(t, srv.URL)
+			require.NoError(t, svc.store.Set(context.Background(), oldestPatterns))
+			require.NoError(t, svc.store.Set(context.Background(), oldPatterns))
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			versions, err := svc.Versions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 3)
+			require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+
+			// GCOM serves a version older than the pinned one
+			olderSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				require.NoError(t, json.NewEncoder(w).Encode(oldestPatterns))
+			}))
+			t.Cleanup(olderSrv.Close)
+			svc.client.BaseURL = olderSrv.URL
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.Equal(t, oldPatterns, svc.patterns)
+			pin, ok, err := svc.Pin(context.Background())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, versions[1].Hash, pin.Hash)
+		})
+
+		t.Run("ReleasePin", func(t *testing.T) {
+			svc, oldHash := setup(t)
+			require.NoError(t, svc.Rollback(context.Background(), oldHash))
+			require.NoError(t, svc.ReleasePin(context.Background()))
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			checkMockDetectors(t, svc)
+		})
+
+		t.Run("version not found", func(t *testing.T) {
+			svc, _ := setup(t)
+			err := svc.Rollback(context.Background(), "does-not-exist")
+			require.ErrorIs(t, err, angularpatternsstore.ErrVersionNotFound)
+			checkMockDetectors(t, svc)
+		})
+
+		t.Run("store lock", func(t *testing.T) {
+			t.Run("rollback and pin release hold the store lock", func(t *testing.T) {
+				svc, oldHash := setup(t)
+				lock := &fakeRefreshLock{}
+				svc.lock = lock
+
+				require.NoError(t, svc.Rollback(context.Background(), oldHash))
+				require.NoError(t, svc.ReleasePin(context.Background()))
+				require.Equal(t, 2, lock.storeLockCalls.calls())
+			})
+
+			t.Run("updates hold the store lock", func(t *testing.T) {
+				svc := provideDynamic(t, srv.URL, provideDynamicOpts{lock: &fakeRefreshLock{acquire: true}})
+				lock := svc.lock.(*fakeRefreshLock)
+
+				require.NoError(t, svc.scheduledUpdate(context.Background()))
+				require.True(t, lock.storeLockCalls.calledOnce())
+				checkMockDetectors(t, svc)
+			})
+
+			t.Run("changes are refused while another instance holds the store lock", func(t *testing.T) {
+				svc, oldHash := setup(t)
+				svc.lock = &fakeRefreshLock{storeLocked: true}
+
+				require.ErrorIs(t, svc.Rollback(context.Background(), oldHash), ErrPatternsLocked)
+				require.ErrorIs(t, svc.ReleasePin(context.Background()), ErrPatternsLocked)
+				checkMockDetectors(t, svc)
+				_, ok, err := svc.Pin(context.Background())
+				require.NoError(t, err)
+				require.False(t, ok)
+			})
+		})
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		t.Run("notifies subscribers when detectors change", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			updates := svc.Subscribe(ctx)
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			select {
+			case ev := <-updates:
+				require.Equal(t, ProviderDynamic, ev.Provider)
+				require.WithinDuration(t, time.Now(), ev.UpdatedAt, time.Second*10)
+			default:
+				t.Fatal("subscriber should be notified")
+			}
+		})
+
+		t.Run("does not notify subscribers if detectors did not change", func(t *testing.T) {
+			errSrv := newError500GCOMScenario().newHTTPTestServer()
+			t.Cleanup(errSrv.Close)
+			svc := provideDynamic(t, errSrv.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			updates := svc.Subscribe(ctx)
+
+			require.Error(t, svc.updateDetectors(context.Background()))
+			select {
+			case <-updates:
+				t.Fatal("subscriber should not be notified")
+			default:
+			}
+		})
+
+		t.Run("does not block if subscriber is not consuming events", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			updates := svc.Subscribe(ctx)
+
+			require.NoError(t, svc.Refresh(context.Background()))
+			require.NoError(t, svc.Refresh(context.Background()))
+			<-updates
+			select {
+			case <-updates:
+				t.Fatal("pending events should be coalesced")
+			default:
+			}
+		})
+
+		t.Run("channel is closed when context is done", func(t *testing.T) {
+			svc := provideDynamic(t, srv.URL)
+			ctx, cancel := context.WithCancel(context.Background())
+			updates := svc.Subscribe(ctx)
+			cancel()
+			require.Eventually(t, func() bool {
+				_, ok := <-updates
+				return !ok
+			}, time.Second*5, time.Millisecond*10)
+		})
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		fastBackoff := backoff.Config{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 5, MaxRetries: 3}
+
+		t.Run("transient errors are retried", func(t *testing.T) {
+			var calls counter
+			scenario := &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+				calls.inc()
+				if calls.calls() < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				mockGCOMHTTPHandlerFunc(w, req)
+			}}
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{fetchBackoff: fastBackoff})
+
+			require.NoError(t, svc.updateDetectors(context.Background()))
+			require.True(t, scenario.httpCalls.calledX(3), "gcom api should be called three times")
+			require.Equal(t, float64(2), testutil.ToFloat64(svc.client.Metrics.FetchRetries))
+			require.Zero(t, testutil.ToFloat64(svc.client.Metrics.FetchErrors))
+			checkMockDetectors(t, svc)
+		})
+
+		t.Run("gives up after max retries", func(t *testing.T) {
+			scenario := newError500GCOMScenario()
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{fetchBackoff: fastBackoff})
+
+			err := svc.updateDetectors(context.Background())
+			require.ErrorAs(t, err, &remoterules.UnexpectedStatusCodeError{})
+			require.True(t, scenario.httpCalls.calledX(3), "gcom api should be called three times")
+			require.Equal(t, float64(1), testutil.ToFloat64(svc.client.Metrics.FetchErrors))
+		})
+
+		t.Run("non-transient errors are not retried", func(t *testing.T) {
+			scenario := &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}}
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{fetchBackoff: fastBackoff})
+
+			require.Error(t, svc.updateDetectors(context.Background()))
+			require.True(t, scenario.httpCalls.calledOnce(), "gcom api should be called once")
+			require.Zero(t, testutil.ToFloat64(svc.client.Metrics.FetchRetries))
+		})
+
+		t.Run("rate limited requests are not retried", func(t *testing.T) {
+			scenario := newDefaultGCOMScenario()
+			srv := scenario.newHTTPTestServer()
+			t.Cleanup(srv.Close)
+			svc := provideDynamic(t, srv.URL, provideDynamicOpts{fetchBackoff: fastBackoff})
+			gcomClient := gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{RequestsPerMinute: 1}, "", nil)
+			svc.client.HTTPClient = gcomClient.HTTPClient(feedName, svc.client.HTTPClient.Timeout)
+
+			// The first request uses the whole request budget
+			require.NoError(t, svc.updateDetectors(context.Background()))
+
+			ctx, canc := context.WithTimeout(context.Background(), time.Millisecond*100)
+			t.Cleanup(canc)
+			require.ErrorIs(t, svc.updateDetectors(ctx), gcomclient.ErrRateLimited)
+			require.True(t, scenario.httpCalls.calledOnce(), "gcom api should be called once")
+			require.Zero(t, testutil.ToFloat64(svc.client.Metrics.FetchRetries))
+		})
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		var fail atomic.Bool
+		fail.Store(true)
+		scenario := &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+			if fail.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			mockGCOMHTTPHandlerFunc(w, req)
+		}}
+		srv := scenario.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+		svc := provideDynamic(t, srv.URL)
+		now := time.Now()
+		svc.client.Breaker.Now = func() time.Time { return now }
+
+		// Consecutive failures open the breaker
+		for i := 0; i < remoterules.CircuitBreakerFailureThreshold; i++ {
+			require.Equal(t, remoterules.CircuitBreakerClosed, svc.CircuitBreakerState())
+			require.Error(t, svc.updateDetectors(context.Background()))
+		}
+		require.Equal(t, remoterules.CircuitBreakerOpen, svc.CircuitBreakerState())
+		require.Equal(t, float64(1), testutil.ToFloat64(svc.client.Metrics.CircuitBreakerOpen))
+
+		// GCOM is not called while the breaker is open
+		require.ErrorIs(t, svc.updateDetectors(context.Background()), remoterules.ErrCircuitOpen)
+		require.True(t, scenario.httpCalls.calledX(remoterules.CircuitBreakerFailureThreshold), "gcom api should not be called")
+
+		// A successful trial fetch after the cooldown closes the breaker
+		fail.Store(false)
+		now = now.Add(remoterules.CircuitBreakerCooldown)
+		require.NoError(t, svc.updateDetectors(context.Background()))
+		require.Equal(t, remoterules.CircuitBreakerClosed, svc.CircuitBreakerState())
+		require.Zero(t, testutil.ToFloat64(svc.client.Metrics.CircuitBreakerOpen))
+		checkMockDetectors(t, svc)
+	})
+
+	t.Run("remote cache backend", func(t *testing.T) {
+		gcom := newDefaultGCOMScenario()
+		srv := gcom.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+
+		store := angularpatternsstore.ProvideRemoteCacheService(remotecache.NewFakeCacheStorage(), kvstore.NewFakeKVStore())
+		angularDetection := setting.AngularDetectionSettings{
+			CacheBackend:    setting.AngularPatternsCacheBackendRemoteCache,
+			RefreshInterval: time.Hour,
+		}
+		leaderLock, followerLock := &fakeRefreshLock{acquire: true}, &fakeRefreshLock{}
+		leader := provideDynamic(t, srv.URL, provideDynamicOpts{store: store, angularDetection: angularDetection, lock: leaderLock})
+		follower := provideDynamic(t, srv.URL, provideDynamicOpts{store: store, angularDetection: angularDetection, lock: followerLock})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		followerUpdates := follower.Subscribe(ctx)
+
+		t.Run("only the instance holding the lock fetches from gcom", func(t *testing.T) {
+			require.NoError(t, leader.scheduledUpdate(context.Background()))
+			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should be called once")
+			require.Equal(t, time.Minute*54, leaderLock.maxInterval)
+			checkMockDetectors(t, leader)
+			require.Equal(t, CacheSourceRemote, leader.CacheSource())
+
+			require.NoError(t, follower.scheduledUpdate(context.Background()))
+			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should not be called again")
+			checkMockDetectors(t, follower)
+			require.Equal(t, CacheSourceRemoteCache, follower.CacheSource())
+			require.Equal(t, leader.Provenance().Hash, follower.Provenance().Hash)
+
+			select {
+			case ev := <-followerUpdates:
+				require.Equal(t, ProviderDynamic, ev.Provider)
+			default:
+				require.Fail(t, "follower subscribers should be notified")
+			}
+		})
+
+		t.Run("follower does not reload unchanged patterns", func(t *testing.T) {
+			require.NoError(t, follower.scheduledUpdate(context.Background()))
+			select {
+			case <-followerUpdates:
+				require.Fail(t, "follower subscribers should not be notified")
+			default:
+			}
+		})
+	})
+
 	t.Run("setDetectorsFromCache", func(t *testing.T) {
 		t.Run("empty store doesn't return an error", func(t *testing.T) {
 			svc := provideDynamic(t, srv.URL)
@@ -254,6 +1249,9 @@ func TestDynamicAngularDetectorsProviderBackgroundService(t *testing.T) {
 
 			// Ensure the detectors are initially empty
 			require.Empty(t, svc.ProvideDetectors(context.Background()))
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			updates := svc.Subscribe(ctx)
 
 			// Start bg service and it should call GCOM immediately
 			bg := newBackgroundServiceScenario(svc)
@@ -269,7 +1267,13 @@ func TestDynamicAngularDetectorsProviderBackgroundService(t *testing.T) {
 			}
 			require.True(t, gcom.httpCalls.calledOnce(), "gcom api should be called once")
 
-			// Check new cached value
+			// Check new cached value, once the detectors have been updated
+			select {
+			case <-time.After(time.Second * 10):
+				t.Fatal("timeout")
+			case <-updates:
+				break
+			}
 			checkMockDetectors(t, svc)
 			bg.exitAndWait()
 		})
@@ -349,6 +1353,25 @@ func checkMockDetectors(t *testing.T, d *Dynamic) {
 	checkMockDetectorsSlice(t, d.ProvideDetectors(context.Background()))
 }
 
+// mockGCOMResponseCompact returns mockGCOMPatterns JSON-encoded as they are stored in the angular patterns store.
+func mockGCOMResponseCompact(t *testing.T) []byte {
+	b, err := json.Marshal(newMockGCOMPatterns())
+	require.NoError(t, err)
+	return b
+}
+
+// newTestPGPKey generates a new PGP key and returns it alongside its armored public key.
+func newTestPGPKey(t *testing.T) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("test", "", "test@grafana.com", nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	return entity, buf.String()
+}
+
 func newMockGCOMPatterns() GCOMPatterns {
 	var mockGCOMPatterns GCOMPatterns
 	if err := json.Unmarshal(mockGCOMResponse, &mockGCOMPatterns); err != nil {
@@ -416,6 +1439,21 @@ func newDefaultGCOMScenario(middlewares ...http.HandlerFunc) *gcomScenario {
 	}}
 }
 
+func newEmptyGCOMScenario() *gcomScenario {
+	return &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}}
+}
+
+// newSchemaVersionGCOMScenario returns a gcomScenario that returns the provided patterns in the versioned format.
+func newSchemaVersionGCOMScenario(t *testing.T, schemaVersion int, patterns GCOMPatterns) *gcomScenario {
+	b, err := json.Marshal(gcomPatternsResponse{SchemaVersion: schemaVersion, Patterns: patterns})
+	require.NoError(t, err)
+	return &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(b)
+	}}
+}
+
 func newError500GCOMScenario() *gcomScenario {
 	return &gcomScenario{httpHandlerFunc: func(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -423,7 +1461,15 @@ func newError500GCOMScenario() *gcomScenario {
 }
 
 type provideDynamicOpts struct {
-	store angularpatternsstore.Service
+	store            angularpatternsstore.Service
+	angularDetection setting.AngularDetectionSettings
+
+	// fetchBackoff is the backoff configuration used to retry failed fetches.
+	// If not set, failed fetches are not retried.
+	fetchBackoff backoff.Config
+
+	// lock is the lock used to fetch the patterns from a single instance.
+	lock refreshLock
 }
 
 func provideDynamic(t *testing.T, gcomURL string, opts ...provideDynamicOpts) *Dynamic {
@@ -435,14 +1481,49 @@ func provideDynamic(t *testing.T, gcomURL string, opts ...provideDynamicOpts) *D
 		opt.store = angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
 	}
 	d, err := ProvideDynamic(
-		&config.Cfg{GrafanaComURL: gcomURL},
+		&config.Cfg{GrafanaComURL: gcomURL, AngularDetection: opt.angularDetection},
 		opt.store,
+		gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+		nil,
 		featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+		prometheus.NewRegistry(),
 	)
 	require.NoError(t, err)
+	d.client.Backoff = opt.fetchBackoff
+	if d.client.Backoff.MaxRetries == 0 {
+		d.client.Backoff = backoff.Config{MaxRetries: 1}
+	}
+	d.lock = opt.lock
 	return d
 }
 
+// fakeRefreshLock is a refreshLock that executes the function only if acquire is true.
+// The functions executed with LockExecuteAndRelease are only executed if storeLocked is false.
+type fakeRefreshLock struct {
+	acquire     bool
+	maxInterval time.Duration
+
+	storeLocked    bool
+	storeLockCalls counter
+}
+
+func (l *fakeRefreshLock) LockAndExecute(ctx context.Context, _ string, maxInterval time.Duration, fn func(ctx context.Context)) error {
+	l.maxInterval = maxInterval
+	if l.acquire {
+		fn(ctx)
+	}
+	return nil
+}
+
+func (l *fakeRefreshLock) LockExecuteAndRelease(ctx context.Context, _ string, _ time.Duration, fn func(ctx context.Context)) error {
+	l.storeLockCalls.inc()
+	if l.storeLocked {
+		return &serverlock.ServerLockExistsError{}
+	}
+	fn(ctx)
+	return nil
+}
+
 // mockLastUpdatePatternsStore wraps an angularpatternsstore.Service and returns a pre-defined value (lastUpdated)
 // when calling GetLastUpdated. All other method calls are sent to the wrapped angularpatternsstore.Service.
 type mockLastUpdatePatternsStore struct {
@@ -455,6 +1536,26 @@ func (s *mockLastUpdatePatternsStore) GetLastUpdated(_ context.Context) (time.Ti
 	return s.lastUpdated, nil
 }
 
+// failingSetPatternsStore wraps an angularpatternsstore.Service and fails to store the patterns.
+type failingSetPatternsStore struct {
+	angularpatternsstore.Service
+}
+
+func (s *failingSetPatternsStore) SetWithSchemaVersion(_ context.Context, _ any, _ int) error {
+	return errors.New("set failed")
+}
+
+// setLastUpdatedCounterStore wraps an angularpatternsstore.Service and counts the calls to SetLastUpdated.
+type setLastUpdatedCounterStore struct {
+	angularpatternsstore.Service
+	calls counter
+}
+
+func (s *setLastUpdatedCounterStore) SetLastUpdated(ctx context.Context) error {
+	s.calls.inc()
+	return s.Service.SetLastUpdated(ctx)
+}
+
 type backgroundServiceScenario struct {
 	svc         *Dynamic
 	wg          sync.WaitGroup
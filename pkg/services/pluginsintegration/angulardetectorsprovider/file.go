@@ -0,0 +1,205 @@
+package angulardetectorsprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// angularPatternsProvisioningDir is the directory, relative to the provisioning path, containing the angular
+// detection patterns files.
+const angularPatternsProvisioningDir = "angular-patterns"
+
+// filePollInterval is the interval between two checks for changes of the patterns files.
+// It can be overwritten in tests.
+var filePollInterval = time.Second * 10
+
+// File is an angulardetector.DetectorsProvider that reads Angular detection patterns from the JSON files in a
+// provisioning directory, converts them to detectors and caches them for all future calls.
+// Each file must contain a list of patterns in the same format returned by the GCOM API.
+// It also provides a background service that polls the directory and reloads the patterns when files change.
+// If the directory does not exist, the background service is disabled and no detectors are provided.
+type File struct {
+	log log.Logger
+
+	// dir is the directory containing the patterns files.
+	dir string
+
+	// detectors contains the cached angular detectors, which are created from the patterns files.
+	// mux should be acquired before reading from/writing to this field.
+	detectors []angulardetector.AngularDetector
+
+	// namedDetectors contains the cached angular detectors, named after the patterns they have been created from.
+	// mux should be acquired before reading from/writing to this field.
+	namedDetectors []angulardetector.NamedDetector
+
+	// mux is the mutex used to read/write the cached detectors in a concurrency-safe way.
+	mux sync.RWMutex
+
+	// files contains the state of the patterns files the cached detectors have been loaded from.
+	// It is only accessed by load and the background service, which never run concurrently.
+	files filesState
+
+	// subscribers are notified every time the cached detectors change.
+	subscribers subscribers
+}
+
+func ProvideFile(cfg *setting.Cfg) *File {
+	f := &File{
+		log: log.New("plugin.angulardetectorsprovider.file"),
+		dir: filepath.Join(cfg.ProvisioningPath, angularPatternsProvisioningDir),
+	}
+	if f.IsDisabled() {
+		return f
+	}
+	if err := f.load(); err != nil {
+		f.log.Warn("Could not load angular patterns files", "dir", f.dir, "error", err)
+	}
+	return f
+}
+
+// IsDisabled returns true if the patterns directory does not exist.
+func (f *File) IsDisabled() bool {
+	st, err := os.Stat(f.dir)
+	return err != nil || !st.IsDir()
+}
+
+// load reads all the patterns files in the directory, in lexical order, and updates the cached detectors.
+// If any file cannot be read or converted to detectors, the cached detectors are not modified.
+func (f *File) load() error {
+	files, err := f.readFilesState()
+	if err != nil {
+		return err
+	}
+	// Record the state even if the files are invalid, so they are not reloaded until they change again
+	f.files = files
+	var patterns GCOMPatterns
+	for _, file := range files {
+		fn := filepath.Join(f.dir, file.name)
+		// nolint:gosec
+		// We can ignore the gosec G304 warning since the path is built from the provisioning path
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return fmt.Errorf("read file %q: %w", fn, err)
+		}
+		filePatterns, err := parseGCOMPatterns(b)
+		if err != nil {
+			return fmt.Errorf("json unmarshal %q: %w", fn, err)
+		}
+		patterns = append(patterns, filePatterns.Patterns...)
+	}
+	detectors, _, err := patternsToDetectors(f.log, patterns)
+	if err != nil {
+		return fmt.Errorf("patterns convert to detectors: %w", err)
+	}
+	namedDetectors := patternsToNamedDetectors(f.log, patterns)
+
+	f.mux.Lock()
+	f.detectors = detectors
+	f.namedDetectors = namedDetectors
+	f.mux.Unlock()
+	f.subscribers.publish(DetectorsUpdated{Provider: ProviderFile, UpdatedAt: time.Now()})
+	f.log.Debug("Loaded angular patterns files", "dir", f.dir, "patterns", len(patterns))
+	return nil
+}
+
+// fileState is the state of a patterns file, used to detect changes.
+type fileState struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// filesState is the state of all the patterns files in the directory, in lexical order.
+type filesState []fileState
+
+func (s filesState) equal(other filesState) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for i := range s {
+		if s[i].name != other[i].name || s[i].size != other[i].size || !s[i].modTime.Equal(other[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// readFilesState returns the state of the patterns files in the directory.
+func (f *File) readFilesState() (filesState, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+	var r filesState
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("file info %q: %w", e.Name(), err)
+		}
+		r = append(r, fileState{name: e.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	return r, nil
+}
+
+// Run is the function implementing the background service and periodically checks the patterns files, reloading
+// the detectors when files are added, modified or removed.
+func (f *File) Run(ctx context.Context) error {
+	f.log.Debug("Started background service", "dir", f.dir)
+	ticker := time.NewTicker(filePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			files, err := f.readFilesState()
+			if err != nil {
+				f.log.Error("Could not check angular patterns files", "dir", f.dir, "error", err)
+				continue
+			}
+			if files.equal(f.files) {
+				continue
+			}
+			f.log.Debug("Angular patterns files changed", "dir", f.dir)
+			if err := f.load(); err != nil {
+				f.log.Error("Could not reload angular patterns files", "dir", f.dir, "error", err)
+				continue
+			}
+			f.log.Info("Reloaded angular patterns files", "dir", f.dir)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ProvideDetectors returns the cached detectors. It returns an empty slice if there's no value.
+func (f *File) ProvideDetectors(_ context.Context) []angulardetector.AngularDetector {
+	f.mux.RLock()
+	r := f.detectors
+	f.mux.RUnlock()
+	return r
+}
+
+// ProvideNamedDetectors returns the cached detectors, named after the patterns they have been created from.
+// Patterns of type GCOMPatternTypeSuppress are returned as suppressed detectors.
+func (f *File) ProvideNamedDetectors(_ context.Context) []angulardetector.NamedDetector {
+	f.mux.RLock()
+	r := f.namedDetectors
+	f.mux.RUnlock()
+	return r
+}
+
+// Subscribe returns a channel that receives a DetectorsUpdated event every time the patterns files are reloaded.
+// The channel is closed when the provided context is done.
+func (f *File) Subscribe(ctx context.Context) <-chan DetectorsUpdated {
+	return f.subscribers.subscribe(ctx)
+}
@@ -0,0 +1,96 @@
+package angulardetectorsprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestFileAngularDetectorsProvider(t *testing.T) {
+	newProvisioningDir := func(t *testing.T) (string, string) {
+		provisioningPath := t.TempDir()
+		dir := filepath.Join(provisioningPath, angularPatternsProvisioningDir)
+		require.NoError(t, os.Mkdir(dir, 0750))
+		return provisioningPath, dir
+	}
+
+	t.Run("is disabled if directory does not exist", func(t *testing.T) {
+		svc := ProvideFile(&setting.Cfg{ProvisioningPath: t.TempDir()})
+		require.True(t, svc.IsDisabled())
+		require.Empty(t, svc.ProvideDetectors(context.Background()))
+	})
+
+	t.Run("loads patterns from files", func(t *testing.T) {
+		provisioningPath, dir := newProvisioningDir(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "patterns.json"), mockGCOMResponse, 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a patterns file"), 0600))
+
+		svc := ProvideFile(&setting.Cfg{ProvisioningPath: provisioningPath})
+		require.False(t, svc.IsDisabled())
+		checkMockDetectorsSlice(t, svc.ProvideDetectors(context.Background()))
+	})
+
+	t.Run("provides named and suppressed detectors", func(t *testing.T) {
+		provisioningPath, dir := newProvisioningDir(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "patterns.json"), []byte(`[
+			{"name": "PanelCtrl", "type": "suppress"},
+			{"name": "QueryCtrl", "type": "contains", "pattern": "QueryCtrl"}
+		]`), 0600))
+
+		svc := ProvideFile(&setting.Cfg{ProvisioningPath: provisioningPath})
+		require.Equal(t, []angulardetector.NamedDetector{
+			{Name: "PanelCtrl", Suppressed: true},
+			{Name: "QueryCtrl", Detector: &angulardetector.ContainsBytesDetector{Pattern: []byte("QueryCtrl")}},
+		}, svc.ProvideNamedDetectors(context.Background()))
+		require.Len(t, svc.ProvideDetectors(context.Background()), 1)
+	})
+
+	t.Run("invalid file does not set detectors", func(t *testing.T) {
+		provisioningPath, dir := newProvisioningDir(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "patterns.json"), []byte(`{"invalid"`), 0600))
+
+		svc := ProvideFile(&setting.Cfg{ProvisioningPath: provisioningPath})
+		require.Empty(t, svc.ProvideDetectors(context.Background()))
+	})
+
+	t.Run("reloads patterns when files change", func(t *testing.T) {
+		origPollInterval := filePollInterval
+		filePollInterval = time.Millisecond * 10
+		t.Cleanup(func() { filePollInterval = origPollInterval })
+
+		provisioningPath, dir := newProvisioningDir(t)
+		svc := ProvideFile(&setting.Cfg{ProvisioningPath: provisioningPath})
+		require.Empty(t, svc.ProvideDetectors(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		updates := svc.Subscribe(ctx)
+		done := make(chan error)
+		go func() {
+			done <- svc.Run(ctx)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			require.ErrorIs(t, <-done, context.Canceled)
+		})
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "patterns.json"), mockGCOMResponse, 0600))
+		require.Eventually(t, func() bool {
+			return len(svc.ProvideDetectors(context.Background())) > 0
+		}, time.Second*5, time.Millisecond*10)
+		checkMockDetectorsSlice(t, svc.ProvideDetectors(context.Background()))
+
+		select {
+		case ev := <-updates:
+			require.Equal(t, ProviderFile, ev.Provider)
+		case <-time.After(time.Second * 5):
+			t.Fatal("subscriber should be notified")
+		}
+	})
+}
@@ -1,29 +1,51 @@
 package angulardetectorsprovider
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 
+	"github.com/grafana/grafana/pkg/plugins/log"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
 )
 
 // gcomAngularPatternsPath is the relative path to the GCOM API handler that returns angular detection patterns.
 const gcomAngularPatternsPath = "/api/plugins/angular_patterns"
 
+// gcomAngularPatternsSignaturePath is the relative path to the GCOM API handler that returns the armored
+// detached signature of the angular detection patterns.
+const gcomAngularPatternsSignaturePath = "/api/plugins/angular_patterns/signature"
+
 // GCOMPatternType is a pattern type returned by the GCOM API.
 type GCOMPatternType string
 
 const (
 	GCOMPatternTypeContains GCOMPatternType = "contains"
 	GCOMPatternTypeRegex    GCOMPatternType = "regex"
+
+	// GCOMPatternTypeStructural is a pattern type that matches a structural condition on the tokens of the module,
+	// such as "import:app/plugins/sdk", rather than a substring. See angulardetector.StructuralDetector.
+	GCOMPatternTypeStructural GCOMPatternType = "structural"
+
+	// GCOMPatternTypeSuppress is a pattern type that removes the pattern with the same name coming from
+	// lower-precedence providers. It is meant to be used in provisioned patterns files.
+	GCOMPatternTypeSuppress GCOMPatternType = "suppress"
 )
 
+// GCOMPatternSeverity is the severity of a pattern returned by the GCOM API.
+type GCOMPatternSeverity string
+
+// GCOMPatternSeverityCritical is the severity of patterns that can be used to block plugin installations.
+const GCOMPatternSeverityCritical GCOMPatternSeverity = "critical"
+
 // GCOMPattern is an Angular detection pattern returned by the GCOM API.
 type GCOMPattern struct {
-	Name    string
-	Pattern string
-	Type    GCOMPatternType
+	Name     string
+	Pattern  string
+	Type     GCOMPatternType
+	Severity GCOMPatternSeverity
 }
 
 var (
@@ -46,9 +68,124 @@ func (p *GCOMPattern) angularDetector() (angulardetector.AngularDetector, error)
 			return nil, fmt.Errorf("%q regexp compile: %w: %s", p.Pattern, errInvalidRegex, err)
 		}
 		return &angulardetector.RegexDetector{Regex: re}, nil
+	case GCOMPatternTypeStructural:
+		d, err := angulardetector.NewStructuralDetector(p.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
 	}
 	return nil, fmt.Errorf("%q: %w", p.Type, errUnknownPatternType)
 }
 
 // GCOMPatterns is a slice of GCOMPattern
 type GCOMPatterns []GCOMPattern
+
+// gcomPatternsResponse is the versioned format of the angular patterns returned by the GCOM API.
+// GCOM versions that predate schema versioning return a plain list of patterns, which is schema version 1.
+type gcomPatternsResponse struct {
+	// SchemaVersion is the schema version of the patterns.
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Patterns contains the angular detection patterns.
+	Patterns GCOMPatterns `json:"patterns"`
+}
+
+// parseGCOMPatterns parses JSON-encoded angular patterns, either in the versioned format or as a plain list of
+// patterns (schema version 1).
+func parseGCOMPatterns(b []byte) (gcomPatternsResponse, error) {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		var patterns GCOMPatterns
+		if err := json.Unmarshal(b, &patterns); err != nil {
+			return gcomPatternsResponse{}, err
+		}
+		return gcomPatternsResponse{SchemaVersion: 1, Patterns: patterns}, nil
+	}
+	var resp gcomPatternsResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return gcomPatternsResponse{}, err
+	}
+	if resp.SchemaVersion == 0 {
+		resp.SchemaVersion = 1
+	}
+	return resp, nil
+}
+
+// patternsToDetectors converts a slice of gcomPattern into a slice of angulardetector.AngularDetector, by calling
+// angularDetector() on each gcomPattern.
+// Patterns with an unknown type are skipped and logged using the provided logger. The number of skipped
+// patterns is returned alongside the detectors.
+func patternsToDetectors(logger log.Logger, patterns GCOMPatterns) ([]angulardetector.AngularDetector, int, error) {
+	var finalErr error
+	var skipped int
+	detectors := make([]angulardetector.AngularDetector, 0, len(patterns))
+	for _, pattern := range patterns {
+		ad, err := pattern.angularDetector()
+		if err != nil {
+			// Fail silently in case of an errUnknownPatternType.
+			// This allows us to introduce new pattern types without breaking old Grafana versions
+			if errors.Is(err, errUnknownPatternType) {
+				logger.Debug("Unknown angular pattern", "name", pattern.Name, "type", pattern.Type, "error", err)
+				skipped++
+				continue
+			}
+			// Other error, do not ignore it
+			finalErr = errors.Join(finalErr, err)
+		}
+		detectors = append(detectors, ad)
+	}
+	if finalErr != nil {
+		return nil, skipped, finalErr
+	}
+	return detectors, skipped, nil
+}
+
+// ParseNamedDetectors parses JSON-encoded angular patterns, in the same format as the patterns returned by GCOM,
+// and converts them to named detectors. Suppressed patterns and patterns that cannot be converted to detectors
+// are skipped and logged using the provided logger.
+func ParseNamedDetectors(logger log.Logger, b []byte) ([]angulardetector.NamedDetector, error) {
+	resp, err := parseGCOMPatterns(b)
+	if err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+	named := patternsToNamedDetectors(logger, resp.Patterns)
+	r := make([]angulardetector.NamedDetector, 0, len(named))
+	for _, d := range named {
+		if d.Suppressed {
+			logger.Debug("Skipping suppressed angular pattern", "name", d.Name)
+			continue
+		}
+		r = append(r, d)
+	}
+	return r, nil
+}
+
+// patternsToNamedDetectors converts a slice of gcomPattern into a slice of angulardetector.NamedDetector, named after
+// the patterns names. Patterns of type GCOMPatternTypeSuppress are converted to suppressed detectors.
+// Patterns that cannot be converted to detectors are skipped and logged using the provided logger.
+func patternsToNamedDetectors(logger log.Logger, patterns GCOMPatterns) []angulardetector.NamedDetector {
+	detectors, _ := compilePatterns(logger, patterns)
+	return detectors
+}
+
+// compilePatterns is like patternsToNamedDetectors, but also returns the patterns the named detectors have been
+// created from, at the same index.
+func compilePatterns(logger log.Logger, patterns GCOMPatterns) ([]angulardetector.NamedDetector, GCOMPatterns) {
+	detectors := make([]angulardetector.NamedDetector, 0, len(patterns))
+	compiled := make(GCOMPatterns, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.Type == GCOMPatternTypeSuppress {
+			detectors = append(detectors, angulardetector.NamedDetector{Name: pattern.Name, Suppressed: true})
+			compiled = append(compiled, pattern)
+			continue
+		}
+		ad, err := pattern.angularDetector()
+		if err != nil {
+			logger.Debug("Skipping angular pattern", "name", pattern.Name, "type", pattern.Type, "error", err)
+			continue
+		}
+		detectors = append(detectors, angulardetector.NamedDetector{Name: pattern.Name, Detector: ad})
+		compiled = append(compiled, pattern)
+	}
+	return detectors, compiled
+}
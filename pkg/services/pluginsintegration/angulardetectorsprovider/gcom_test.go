@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/grafana/grafana/pkg/plugins/log"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
 	"github.com/stretchr/testify/require"
 )
@@ -36,6 +37,21 @@ func TestGCOMPatterns(t *testing.T) {
 				pattern:  GCOMPattern{Name: "test", Pattern: `[`, Type: GCOMPatternTypeRegex},
 				expError: errInvalidRegex,
 			},
+			{
+				name:    "structural",
+				pattern: GCOMPattern{Name: "test", Pattern: "import:app/plugins/sdk", Type: GCOMPatternTypeStructural},
+				exp: func(t *testing.T, d angulardetector.AngularDetector) {
+					require.Equal(t, &angulardetector.StructuralDetector{
+						Condition: angulardetector.StructuralConditionImport,
+						Value:     "app/plugins/sdk",
+					}, d)
+				},
+			},
+			{
+				name:     "invalid structural pattern returns ErrInvalidStructuralPattern",
+				pattern:  GCOMPattern{Name: "test", Pattern: "unknown:abc", Type: GCOMPatternTypeStructural},
+				expError: angulardetector.ErrInvalidStructuralPattern,
+			},
 			{
 				name:     "invalid type returns errUnknownPatternType",
 				pattern:  GCOMPattern{Name: "test", Pattern: "abc", Type: "unknown"},
@@ -52,4 +68,56 @@ func TestGCOMPatterns(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("parseGCOMPatterns", func(t *testing.T) {
+		exp := GCOMPatterns{{Name: "test", Pattern: "pattern", Type: GCOMPatternTypeContains}}
+		for _, c := range []struct {
+			name             string
+			body             string
+			expSchemaVersion int
+		}{
+			{
+				name:             "plain list is schema version 1",
+				body:             `[{"name": "test", "pattern": "pattern", "type": "contains"}]`,
+				expSchemaVersion: 1,
+			},
+			{
+				name:             "versioned format",
+				body:             `{"schemaVersion": 2, "patterns": [{"name": "test", "pattern": "pattern", "type": "contains"}]}`,
+				expSchemaVersion: 2,
+			},
+			{
+				name:             "versioned format without schema version is schema version 1",
+				body:             `{"patterns": [{"name": "test", "pattern": "pattern", "type": "contains"}]}`,
+				expSchemaVersion: 1,
+			},
+		} {
+			t.Run(c.name, func(t *testing.T) {
+				r, err := parseGCOMPatterns([]byte(c.body))
+				require.NoError(t, err)
+				require.Equal(t, c.expSchemaVersion, r.SchemaVersion)
+				require.Equal(t, exp, r.Patterns)
+			})
+		}
+
+		t.Run("invalid json", func(t *testing.T) {
+			_, err := parseGCOMPatterns([]byte(`{`))
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("ParseNamedDetectors", func(t *testing.T) {
+		named, err := ParseNamedDetectors(log.NewTestLogger(), []byte(`{"schemaVersion": 1, "patterns": [
+			{"name": "PanelCtrl", "pattern": "PanelCtrl", "type": "contains"},
+			{"name": "ConfigCtrl", "type": "suppress"},
+			{"name": "Unknown", "pattern": "abc", "type": "unknown"}
+		]}`))
+		require.NoError(t, err)
+		require.Equal(t, []angulardetector.NamedDetector{
+			{Name: "PanelCtrl", Detector: &angulardetector.ContainsBytesDetector{Pattern: []byte("PanelCtrl")}},
+		}, named)
+
+		_, err = ParseNamedDetectors(log.NewTestLogger(), []byte(`{`))
+		require.Error(t, err)
+	})
 }
@@ -0,0 +1,133 @@
+package angulardetectorsprovider
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/log"
+)
+
+// ErrCriticalPatterns is returned by InstallChecker.ValidateArchive when a plugin matches critical angular detection
+// patterns and AngularDetection.BlockCriticalOnInstall is enabled.
+var ErrCriticalPatterns = errors.New("plugin matches critical angular detection patterns")
+
+var _ plugins.ArchiveValidator = (*InstallChecker)(nil)
+
+// InstallChecker checks the archives of the plugins that are about to be installed against the dynamic angular
+// detection patterns, before they are extracted, so plugins matching critical patterns can be rejected without
+// touching the existing installation.
+type InstallChecker struct {
+	log     log.Logger
+	cfg     *config.Cfg
+	dynamic *Dynamic
+
+	// exclusions contains the IDs of the plugins that are never marked as Angular.
+	exclusions map[string]struct{}
+}
+
+func ProvideInstallChecker(cfg *config.Cfg, dynamic *Dynamic) *InstallChecker {
+	exclusions := make(map[string]struct{}, len(cfg.AngularDetection.Exclusions))
+	for _, pluginID := range cfg.AngularDetection.Exclusions {
+		exclusions[pluginID] = struct{}{}
+	}
+	return &InstallChecker{
+		log:        log.New("plugin.angulardetectorsprovider.install"),
+		cfg:        cfg,
+		dynamic:    dynamic,
+		exclusions: exclusions,
+	}
+}
+
+// MatchingPatterns returns the cached patterns matching the module.js of the plugin contained in the provided
+// archive, alongside their provenance. The cached patterns are not refreshed, callers that need fresh patterns
+// should call Dynamic.RefreshIfStale once before installing the plugin.
+// It returns no patterns if the plugin is excluded from the angular detection or if it has no module.js.
+func (c *InstallChecker) MatchingPatterns(_ context.Context, pluginID string, archive *zip.Reader) (GCOMPatterns, Provenance, error) {
+	if _, ok := c.exclusions[pluginID]; ok {
+		return nil, Provenance{}, nil
+	}
+	moduleJs, ok, err := archiveModuleJs(archive, c.cfg.AngularDetection.MaxFileSize)
+	if err != nil {
+		return nil, Provenance{}, fmt.Errorf("read module.js: %w", err)
+	}
+	if !ok {
+		return nil, c.dynamic.Provenance(), nil
+	}
+	patterns, provenance := c.dynamic.MatchingPatterns(moduleJs)
+	return patterns, provenance, nil
+}
+
+// ValidateArchive returns ErrCriticalPatterns if AngularDetection.BlockCriticalOnInstall is enabled and the plugin
+// contained in the provided archive matches critical angular detection patterns.
+func (c *InstallChecker) ValidateArchive(ctx context.Context, pluginID string, archive *zip.Reader) error {
+	if !c.cfg.AngularDetection.BlockCriticalOnInstall {
+		return nil
+	}
+	patterns, provenance, err := c.MatchingPatterns(ctx, pluginID, archive)
+	if err != nil {
+		return err
+	}
+	var critical []string
+	for _, p := range patterns {
+		if p.Severity == GCOMPatternSeverityCritical {
+			critical = append(critical, p.Name)
+		}
+	}
+	if len(critical) == 0 {
+		return nil
+	}
+	c.log.Warn("Blocking plugin installation because it matches critical angular detection patterns", "pluginId", pluginID,
+		"patterns", critical, "patternsSource", provenance.Source, "patternsHash", provenance.Hash,
+		"patternsFetchedAt", provenance.FetchedAt)
+	return fmt.Errorf("%w: %s", ErrCriticalPatterns, strings.Join(critical, ", "))
+}
+
+// archiveModuleJs returns the content of the module.js of the plugin contained in the provided archive, which is
+// next to the plugin.json closest to the root of the archive. At most limit bytes are read, unless limit is 0.
+// The second return value is false if the archive contains no plugin.json or module.js.
+func archiveModuleJs(archive *zip.Reader, limit int64) ([]byte, bool, error) {
+	depth := func(dir string) int {
+		if dir == "." {
+			return 0
+		}
+		return strings.Count(dir, "/") + 1
+	}
+	root, found := "", false
+	for _, f := range archive.File {
+		if path.Base(f.Name) != "plugin.json" {
+			continue
+		}
+		if dir := path.Dir(f.Name); !found || depth(dir) < depth(root) {
+			root, found = dir, true
+		}
+	}
+	if !found {
+		return nil, false, nil
+	}
+	f, err := archive.Open(path.Join(root, "module.js"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Backend plugins may not have a module.js
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer func() { _ = f.Close() }()
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, limit)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
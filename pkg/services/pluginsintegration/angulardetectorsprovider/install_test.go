@@ -0,0 +1,125 @@
+package angulardetectorsprovider
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestInstallChecker(t *testing.T) {
+	patterns := GCOMPatterns{
+		{Name: "PanelCtrl", Pattern: "PanelCtrl", Type: GCOMPatternTypeContains},
+		{Name: "LegacySDK", Pattern: "app/plugins/sdk", Type: GCOMPatternTypeContains, Severity: GCOMPatternSeverityCritical},
+	}
+	store := angularpatternsstore.ProvideService(kvstore.NewFakeKVStore())
+	require.NoError(t, store.Set(context.Background(), patterns))
+	dynamic := provideDynamic(t, "", provideDynamicOpts{store: store})
+
+	newChecker := func(angularDetection setting.AngularDetectionSettings) *InstallChecker {
+		return ProvideInstallChecker(&config.Cfg{AngularDetection: angularDetection}, dynamic)
+	}
+
+	t.Run("matching patterns", func(t *testing.T) {
+		for _, tc := range []struct {
+			name        string
+			files       map[string]string
+			expPatterns []string
+		}{
+			{
+				name:        "module.js next to plugin.json",
+				files:       map[string]string{"test/plugin.json": "{}", "test/module.js": "PanelCtrl"},
+				expPatterns: []string{"PanelCtrl"},
+			},
+			{
+				name:        "module.js in dist",
+				files:       map[string]string{"test/dist/plugin.json": "{}", "test/dist/module.js": "app/plugins/sdk"},
+				expPatterns: []string{"LegacySDK"},
+			},
+			{
+				name: "nested plugins are ignored",
+				files: map[string]string{
+					"test/plugin.json": "{}", "test/module.js": "react",
+					"test/nested/plugin.json": "{}", "test/nested/module.js": "PanelCtrl",
+				},
+			},
+			{
+				name:  "no module.js",
+				files: map[string]string{"test/plugin.json": "{}"},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				matching, _, err := newChecker(setting.AngularDetectionSettings{}).MatchingPatterns(context.Background(), "test", newTestArchive(t, tc.files))
+				require.NoError(t, err)
+				var names []string
+				for _, p := range matching {
+					names = append(names, p.Name)
+				}
+				require.Equal(t, tc.expPatterns, names)
+			})
+		}
+	})
+
+	t.Run("cached patterns are not refreshed", func(t *testing.T) {
+		gcom := newDefaultGCOMScenario()
+		srv := gcom.newHTTPTestServer()
+		t.Cleanup(srv.Close)
+		checker := ProvideInstallChecker(&config.Cfg{}, provideDynamic(t, srv.URL, provideDynamicOpts{store: store}))
+
+		archive := newTestArchive(t, map[string]string{"test/plugin.json": "{}", "test/module.js": "PanelCtrl"})
+		matching, _, err := checker.MatchingPatterns(context.Background(), "test", archive)
+		require.NoError(t, err)
+		require.Len(t, matching, 1)
+		require.False(t, gcom.httpCalls.called(), "gcom api should not be called")
+	})
+
+	t.Run("validate archive", func(t *testing.T) {
+		critical := newTestArchive(t, map[string]string{"test/plugin.json": "{}", "test/module.js": "app/plugins/sdk"})
+		nonCritical := newTestArchive(t, map[string]string{"test/plugin.json": "{}", "test/module.js": "PanelCtrl"})
+
+		t.Run("critical patterns are blocked", func(t *testing.T) {
+			err := newChecker(setting.AngularDetectionSettings{BlockCriticalOnInstall: true}).ValidateArchive(context.Background(), "test", critical)
+			require.ErrorIs(t, err, ErrCriticalPatterns)
+			require.ErrorContains(t, err, "LegacySDK")
+		})
+
+		t.Run("non critical patterns are not blocked", func(t *testing.T) {
+			err := newChecker(setting.AngularDetectionSettings{BlockCriticalOnInstall: true}).ValidateArchive(context.Background(), "test", nonCritical)
+			require.NoError(t, err)
+		})
+
+		t.Run("critical patterns are not blocked if blocking is disabled", func(t *testing.T) {
+			err := newChecker(setting.AngularDetectionSettings{}).ValidateArchive(context.Background(), "test", critical)
+			require.NoError(t, err)
+		})
+
+		t.Run("excluded plugins are not blocked", func(t *testing.T) {
+			err := newChecker(setting.AngularDetectionSettings{BlockCriticalOnInstall: true, Exclusions: []string{"test"}}).
+				ValidateArchive(context.Background(), "test", critical)
+			require.NoError(t, err)
+		})
+	})
+}
+
+// newTestArchive returns a zip archive containing the provided files, keyed by their path.
+func newTestArchive(t *testing.T, files map[string]string) *zip.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return r
+}
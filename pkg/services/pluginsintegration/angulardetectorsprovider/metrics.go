@@ -0,0 +1,65 @@
+package angulardetectorsprovider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubSystem = "plugins"
+)
+
+type metrics struct {
+	emptyResponses      *prometheus.CounterVec
+	patternsLoaded      prometheus.Gauge
+	unknownTypesSkipped prometheus.Counter
+	schemaSkipped       prometheus.Gauge
+	stale               prometheus.Gauge
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		emptyResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "angular_patterns_empty_responses_total",
+			Help:      "Number of empty angular detection patterns responses returned by GCOM",
+		}, []string{"policy"}),
+		patternsLoaded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "angular_patterns_loaded",
+			Help:      "Number of angular detection patterns currently loaded",
+		}),
+		unknownTypesSkipped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "angular_patterns_unknown_types_skipped_total",
+			Help:      "Number of angular detection patterns skipped because of an unknown pattern type",
+		}),
+		schemaSkipped: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "angular_patterns_schema_skipped",
+			Help:      "Number of angular detection patterns of the latest GCOM response skipped because of schema version skew",
+		}),
+		stale: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "angular_patterns_stale",
+			Help:      "Whether the cached angular detection patterns are older than the configured max age (1) or not (0)",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.emptyResponses,
+			m.patternsLoaded,
+			m.unknownTypesSkipped,
+			m.schemaSkipped,
+			m.stale,
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,47 @@
+package angulardetectorsprovider
+
+import (
+	"time"
+)
+
+// gcomPatternsSchemaVersion is the highest schema version of the angular patterns returned by the GCOM API that is
+// fully understood by this version of Grafana.
+const gcomPatternsSchemaVersion = 1
+
+// Provenance contains information about where a set of angular detection patterns comes from.
+type Provenance struct {
+	// Source is the URL or the file path the patterns have been read from.
+	Source string
+
+	// FetchedAt is the time when the patterns have been fetched from the source.
+	FetchedAt time.Time
+
+	// Hash is the hash of the JSON-encoded patterns, as computed by angularpatternsstore.PatternsHash.
+	Hash string
+
+	// SchemaVersion is the schema version of the patterns.
+	SchemaVersion int
+}
+
+// SchemaStatus contains information about the schema of the latest angular patterns fetched from GCOM.
+type SchemaStatus struct {
+	// SchemaVersion is the schema version of the latest patterns fetched from GCOM.
+	// It is zero if no patterns have been fetched from GCOM yet.
+	SchemaVersion int
+
+	// SupportedSchemaVersion is the highest schema version supported by this version of Grafana.
+	SupportedSchemaVersion int
+
+	// SkippedPatterns is the number of patterns of the latest fetched patterns that have been skipped because
+	// their type is not supported by this version of Grafana.
+	SkippedPatterns int
+
+	// KeptPrevious is true if the latest fetched patterns have not been applied because they are not fully
+	// supported, and the previous, fully supported, patterns are still in use.
+	KeptPrevious bool
+}
+
+// HasVersionSkew returns true if the latest fetched patterns are not fully supported by this version of Grafana.
+func (s SchemaStatus) HasVersionSkew() bool {
+	return s.SkippedPatterns > 0 || s.SchemaVersion > s.SupportedSchemaVersion
+}
@@ -0,0 +1,66 @@
+package angulardetectorsprovider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// ProviderDynamic is the name of the Dynamic provider, used in DetectorsUpdated events.
+	ProviderDynamic = "dynamic"
+
+	// ProviderFile is the name of the File provider, used in DetectorsUpdated events.
+	ProviderFile = "file"
+)
+
+// DetectorsUpdated is the event sent to subscribers when the detectors returned by a provider change.
+type DetectorsUpdated struct {
+	// Provider is the name of the provider whose detectors changed.
+	Provider string
+
+	// UpdatedAt is the time when the detectors changed.
+	UpdatedAt time.Time
+}
+
+// subscribers keeps track of the channels of the subscribers to detectors updates.
+// The zero value is ready to use.
+type subscribers struct {
+	chans map[chan DetectorsUpdated]struct{}
+	mux   sync.Mutex
+}
+
+// subscribe returns a channel that receives an event every time the detectors change.
+// The channel is closed when the provided context is done.
+// Events are not queued: if the subscriber is not keeping up, only one pending event is kept.
+func (s *subscribers) subscribe(ctx context.Context) <-chan DetectorsUpdated {
+	ch := make(chan DetectorsUpdated, 1)
+	s.mux.Lock()
+	if s.chans == nil {
+		s.chans = make(map[chan DetectorsUpdated]struct{})
+	}
+	s.chans[ch] = struct{}{}
+	s.mux.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mux.Lock()
+		delete(s.chans, ch)
+		close(ch)
+		s.mux.Unlock()
+	}()
+	return ch
+}
+
+// publish sends the provided event to all subscribers, without blocking.
+func (s *subscribers) publish(ev DetectorsUpdated) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for ch := range s.chans {
+		select {
+		case ch <- ev:
+		default:
+			// An event is already pending, which is enough for the subscriber to know that the detectors changed
+		}
+	}
+}
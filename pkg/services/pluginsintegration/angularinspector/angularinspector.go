@@ -1,28 +1,83 @@
 package angularinspector
 
 import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type Service struct {
 	angularinspector.Inspector
+
+	patternsListInspector *angularinspector.PatternsListInspector
+
+	// exclusions contains the IDs of the plugins that are never marked as Angular.
+	exclusions map[string]struct{}
 }
 
-func ProvideService(cfg *config.Cfg, dynamic *angulardetectorsprovider.Dynamic) (*Service, error) {
+func ProvideService(cfg *config.Cfg, dynamic *angulardetectorsprovider.Dynamic, file *angulardetectorsprovider.File) (*Service, error) {
 	var detectorsProvider angulardetector.DetectorsProvider
 	var err error
 	static := angularinspector.NewDefaultStaticDetectorsProvider()
+	// Provisioned patterns files are merged on top of the other patterns, and can replace or suppress them by name.
+	// The static patterns are only used if there are no dynamic patterns.
 	if cfg.Features != nil && cfg.Features.IsEnabled(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns) {
-		detectorsProvider = angulardetector.SequenceDetectorsProvider{dynamic, static}
+		detectorsProvider = angulardetector.ChainDetectorsProvider{file, angulardetector.SequenceDetectorsProvider{dynamic, static}}
 	} else {
-		detectorsProvider = static
+		detectorsProvider = angulardetector.ChainDetectorsProvider{file, static}
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &Service{Inspector: &angularinspector.PatternsListInspector{DetectorsProvider: detectorsProvider}}, nil
+	inspector := &angularinspector.PatternsListInspector{
+		DetectorsProvider: detectorsProvider,
+		ScanAllJS:         cfg.AngularDetection.ScanScope == setting.AngularScanScopeAllJS,
+		MaxFileSize:       cfg.AngularDetection.MaxFileSize,
+		MaxPluginBytes:    cfg.AngularDetection.MaxPluginBytes,
+	}
+	exclusions := make(map[string]struct{}, len(cfg.AngularDetection.Exclusions))
+	for _, pluginID := range cfg.AngularDetection.Exclusions {
+		exclusions[pluginID] = struct{}{}
+	}
+	return &Service{Inspector: inspector, patternsListInspector: inspector, exclusions: exclusions}, nil
+}
+
+// Inspect returns true if the provided plugin is using Angular.
+// Excluded plugins are never marked as Angular.
+func (s *Service) Inspect(ctx context.Context, p *plugins.Plugin) (bool, error) {
+	if s.isExcluded(p) {
+		return false, nil
+	}
+	return s.Inspector.Inspect(ctx, p)
+}
+
+// MatchingDetectors returns all the detectors that match the provided plugin.
+// It returns no detectors for excluded plugins.
+func (s *Service) MatchingDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.AngularDetector, error) {
+	if s.isExcluded(p) {
+		return nil, nil
+	}
+	return s.patternsListInspector.MatchingDetectors(ctx, p)
+}
+
+// MatchingNamedDetectors returns all the detectors that match the provided plugin, alongside the names of the
+// patterns they have been created from. It returns no detectors for excluded plugins.
+func (s *Service) MatchingNamedDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error) {
+	if s.isExcluded(p) {
+		return nil, nil
+	}
+	return s.patternsListInspector.MatchingNamedDetectors(ctx, p)
+}
+
+// isExcluded returns true if the provided plugin is excluded from the Angular detection via
+// the angular_detection_exclusions setting.
+func (s *Service) isExcluded(p *plugins.Plugin) bool {
+	_, ok := s.exclusions[p.ID]
+	return ok
 }
@@ -2,17 +2,24 @@ package angularinspector
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/config"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
 	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestProvideService(t *testing.T) {
@@ -21,10 +28,13 @@ func TestProvideService(t *testing.T) {
 		dynamic, err := angulardetectorsprovider.ProvideDynamic(
 			pCfg,
 			angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+			gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+			nil,
 			featuremgmt.WithFeatures(featuremgmt.FlagPluginsDynamicAngularDetectionPatterns),
+			prometheus.NewRegistry(),
 		)
 		require.NoError(t, err)
-		inspector, err := ProvideService(pCfg, dynamic)
+		inspector, err := ProvideService(pCfg, dynamic, angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: t.TempDir()}))
 		require.NoError(t, err)
 		require.IsType(t, inspector.Inspector, &angularinspector.PatternsListInspector{})
 		patternsListInspector := inspector.Inspector.(*angularinspector.PatternsListInspector)
@@ -39,18 +49,113 @@ func TestProvideService(t *testing.T) {
 		dynamic, err := angulardetectorsprovider.ProvideDynamic(
 			pCfg,
 			angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+			gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+			nil,
 			featuremgmt.WithFeatures(),
+			prometheus.NewRegistry(),
 		)
 		require.NoError(t, err)
-		inspector, err := ProvideService(pCfg, dynamic)
+		inspector, err := ProvideService(pCfg, dynamic, angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: t.TempDir()}))
 		require.NoError(t, err)
 		require.IsType(t, inspector.Inspector, &angularinspector.PatternsListInspector{})
-		require.IsType(t, inspector.Inspector.(*angularinspector.PatternsListInspector).DetectorsProvider, angulardetector.SequenceDetectorsProvider{})
-		seq := inspector.Inspector.(*angularinspector.PatternsListInspector).DetectorsProvider.(angulardetector.SequenceDetectorsProvider)
+		require.IsType(t, inspector.Inspector.(*angularinspector.PatternsListInspector).DetectorsProvider, angulardetector.ChainDetectorsProvider{})
+		chain := inspector.Inspector.(*angularinspector.PatternsListInspector).DetectorsProvider.(angulardetector.ChainDetectorsProvider)
+		require.Len(t, chain, 2, "should return the correct number of providers")
+		require.IsType(t, chain[0], &angulardetectorsprovider.File{}, "first AngularDetector provided should be file")
+		require.IsType(t, chain[1], angulardetector.SequenceDetectorsProvider{}, "second AngularDetector provided should be a sequence")
+		seq := chain[1].(angulardetector.SequenceDetectorsProvider)
 		require.Len(t, seq, 2, "should return the correct number of providers")
-		require.IsType(t, seq[0], &angulardetectorsprovider.Dynamic{}, "first AngularDetector provided should be gcom")
-		require.IsType(t, seq[1], &angulardetector.StaticDetectorsProvider{}, "second AngularDetector provided should be static")
+		require.IsType(t, seq[0], &angulardetectorsprovider.Dynamic{}, "first sequence AngularDetector provided should be gcom")
+		require.IsType(t, seq[1], &angulardetector.StaticDetectorsProvider{}, "second sequence AngularDetector provided should be static")
 		staticDetectors := seq[1].ProvideDetectors(context.Background())
 		require.NotEmpty(t, staticDetectors, "provided static detectors should not be empty")
 	})
 }
+
+func TestProvideServiceFileOverrides(t *testing.T) {
+	provisioningPath := t.TempDir()
+	dir := filepath.Join(provisioningPath, "angular-patterns")
+	require.NoError(t, os.Mkdir(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "overrides.json"), []byte(`[
+		{"name": "PanelCtrl", "type": "suppress"},
+		{"name": "QueryCtrl", "type": "regex", "pattern": "[\"']QueryCtrl[\"']\\)"},
+		{"name": "editor.html", "type": "contains", "pattern": "legacyEditor"}
+	]`), 0600))
+
+	pCfg := &config.Cfg{Features: featuremgmt.WithFeatures()}
+	dynamic, err := angulardetectorsprovider.ProvideDynamic(
+		pCfg,
+		angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+		gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+		nil,
+		featuremgmt.WithFeatures(),
+		prometheus.NewRegistry(),
+	)
+	require.NoError(t, err)
+	inspector, err := ProvideService(pCfg, dynamic, angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: provisioningPath}))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		moduleJs string
+		exp      bool
+	}{
+		{moduleJs: "PanelCtrl", exp: false},
+		{moduleJs: "editor.html", exp: false},
+		{moduleJs: "legacyEditor", exp: true},
+		{moduleJs: "ConfigCtrl", exp: true},
+		{moduleJs: `"QueryCtrl"`, exp: false},
+		{moduleJs: `"QueryCtrl")`, exp: true},
+	} {
+		t.Run(tc.moduleJs, func(t *testing.T) {
+			angular, err := inspector.Inspect(context.Background(), &plugins.Plugin{
+				FS: plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(tc.moduleJs)}),
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, angular)
+		})
+	}
+}
+
+func TestProvideServiceExclusions(t *testing.T) {
+	pCfg := &config.Cfg{
+		Features:         featuremgmt.WithFeatures(),
+		AngularDetection: setting.AngularDetectionSettings{Exclusions: []string{"excluded-panel"}},
+	}
+	dynamic, err := angulardetectorsprovider.ProvideDynamic(
+		pCfg,
+		angularpatternsstore.ProvideService(kvstore.NewFakeKVStore()),
+		gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil),
+		nil,
+		featuremgmt.WithFeatures(),
+		prometheus.NewRegistry(),
+	)
+	require.NoError(t, err)
+	inspector, err := ProvideService(pCfg, dynamic, angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: t.TempDir()}))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		pluginID string
+		exp      bool
+	}{
+		{pluginID: "excluded-panel", exp: false},
+		{pluginID: "other-panel", exp: true},
+	} {
+		t.Run(tc.pluginID, func(t *testing.T) {
+			p := &plugins.Plugin{
+				JSONData: plugins.JSONData{ID: tc.pluginID},
+				FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte("PanelCtrl")}),
+			}
+			angular, err := inspector.Inspect(context.Background(), p)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, angular)
+
+			detectors, err := inspector.MatchingDetectors(context.Background(), p)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, len(detectors) > 0)
+
+			named, err := inspector.MatchingNamedDetectors(context.Background(), p)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, len(named) > 0)
+		})
+	}
+}
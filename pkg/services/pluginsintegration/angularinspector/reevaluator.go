@@ -0,0 +1,115 @@
+package angularinspector
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
+	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+)
+
+// inspectTimeout is the maximum time spent inspecting a single plugin.
+const inspectTimeout = time.Second * 10
+
+// detectorsSubscriber is a detectors provider that notifies subscribers when its detectors change.
+type detectorsSubscriber interface {
+	Subscribe(ctx context.Context) <-chan angulardetectorsprovider.DetectorsUpdated
+	IsDisabled() bool
+}
+
+// Reevaluator is a background service that inspects the installed external plugins again every time the angular
+// detection patterns change, and updates their AngularDetected flag accordingly.
+// This way, already-loaded plugins do not keep a stale classification until Grafana is restarted.
+type Reevaluator struct {
+	log         log.Logger
+	cfg         *config.Cfg
+	inspector   angularinspector.Inspector
+	registry    registry.Service
+	subscribers []detectorsSubscriber
+}
+
+func ProvideReevaluator(cfg *config.Cfg, inspector *Service, pluginRegistry registry.Service, dynamic *angulardetectorsprovider.Dynamic, file *angulardetectorsprovider.File) *Reevaluator {
+	return newReevaluator(cfg, inspector, pluginRegistry, dynamic, file)
+}
+
+func newReevaluator(cfg *config.Cfg, inspector angularinspector.Inspector, pluginRegistry registry.Service, subscribers ...detectorsSubscriber) *Reevaluator {
+	return &Reevaluator{
+		log:         log.New("plugins.angular.reevaluator"),
+		cfg:         cfg,
+		inspector:   inspector,
+		registry:    pluginRegistry,
+		subscribers: subscribers,
+	}
+}
+
+// IsDisabled returns true if none of the detectors providers can change their detectors at runtime.
+func (r *Reevaluator) IsDisabled() bool {
+	for _, s := range r.subscribers {
+		if !s.IsDisabled() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run is the function implementing the background service.
+// It re-evaluates the installed plugins every time any of the detectors providers notifies a change.
+func (r *Reevaluator) Run(ctx context.Context) error {
+	updates := make(chan angulardetectorsprovider.DetectorsUpdated, 1)
+	for _, s := range r.subscribers {
+		if s.IsDisabled() {
+			continue
+		}
+		go func(ch <-chan angulardetectorsprovider.DetectorsUpdated) {
+			for ev := range ch {
+				select {
+				case updates <- ev:
+				default:
+					// A re-evaluation is already pending
+				}
+			}
+		}(s.Subscribe(ctx))
+	}
+
+	r.log.Debug("Started background service")
+	for {
+		select {
+		case ev := <-updates:
+			r.log.Debug("Angular detectors updated, re-evaluating plugins", "provider", ev.Provider, "updatedAt", ev.UpdatedAt)
+			r.reevaluate(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// reevaluate inspects all the installed external plugins and updates their AngularDetected flag.
+func (r *Reevaluator) reevaluate(ctx context.Context) {
+	st := time.Now()
+	var changed int
+	for _, p := range r.registry.Plugins(ctx) {
+		if !p.IsExternalPlugin() {
+			continue
+		}
+		cctx, canc := context.WithTimeout(ctx, inspectTimeout)
+		angularDetected, err := r.inspector.Inspect(cctx, p)
+		canc()
+		if err != nil {
+			r.log.Warn("Could not inspect plugin for angular", "pluginId", p.ID, "error", err)
+			continue
+		}
+		if angularDetected == p.IsAngularDetected() {
+			continue
+		}
+		p.SetAngularDetected(angularDetected)
+		changed++
+		r.log.Info("Plugin angular detection changed", "pluginId", p.ID, "angularDetected", angularDetected)
+		if angularDetected && !r.cfg.AngularSupportEnabled {
+			r.log.Warn("Plugin is now detected as using Angular, which has been disabled, and will not be loaded after a restart", "pluginId", p.ID)
+		}
+	}
+	r.log.Debug("Re-evaluated plugins", "changed", changed, "duration", time.Since(st))
+}
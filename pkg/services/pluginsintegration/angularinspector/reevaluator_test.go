@@ -0,0 +1,78 @@
+package angularinspector
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
+	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+)
+
+type fakeDetectorsSubscriber struct {
+	updates  chan angulardetectorsprovider.DetectorsUpdated
+	disabled bool
+}
+
+func newFakeDetectorsSubscriber() *fakeDetectorsSubscriber {
+	return &fakeDetectorsSubscriber{updates: make(chan angulardetectorsprovider.DetectorsUpdated, 1)}
+}
+
+func (s *fakeDetectorsSubscriber) Subscribe(_ context.Context) <-chan angulardetectorsprovider.DetectorsUpdated {
+	return s.updates
+}
+
+func (s *fakeDetectorsSubscriber) IsDisabled() bool {
+	return s.disabled
+}
+
+func TestReevaluator(t *testing.T) {
+	t.Run("IsDisabled", func(t *testing.T) {
+		enabled, disabled := newFakeDetectorsSubscriber(), newFakeDetectorsSubscriber()
+		disabled.disabled = true
+		require.True(t, newReevaluator(&config.Cfg{}, angularinspector.NeverAngularFakeInspector, registry.NewInMemory(), disabled).IsDisabled())
+		require.False(t, newReevaluator(&config.Cfg{}, angularinspector.NeverAngularFakeInspector, registry.NewInMemory(), disabled, enabled).IsDisabled())
+	})
+
+	t.Run("re-evaluates external plugins when detectors are updated", func(t *testing.T) {
+		reg := registry.NewInMemory()
+		external := &plugins.Plugin{JSONData: plugins.JSONData{ID: "external"}, Class: plugins.ClassExternal}
+		core := &plugins.Plugin{JSONData: plugins.JSONData{ID: "core"}, Class: plugins.ClassCore}
+		require.NoError(t, reg.Add(context.Background(), external))
+		require.NoError(t, reg.Add(context.Background(), core))
+
+		var angular atomic.Bool
+		inspector := &angularinspector.FakeInspector{InspectFunc: func(_ context.Context, _ *plugins.Plugin) (bool, error) {
+			return angular.Load(), nil
+		}}
+		sub := newFakeDetectorsSubscriber()
+		r := newReevaluator(&config.Cfg{AngularSupportEnabled: true}, inspector, reg, sub)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- r.Run(ctx)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			require.ErrorIs(t, <-done, context.Canceled)
+		})
+
+		angular.Store(true)
+		sub.updates <- angulardetectorsprovider.DetectorsUpdated{Provider: angulardetectorsprovider.ProviderDynamic, UpdatedAt: time.Now()}
+		require.Eventually(t, external.IsAngularDetected, time.Second*5, time.Millisecond*10)
+		require.False(t, core.IsAngularDetected(), "core plugins should not be re-evaluated")
+
+		angular.Store(false)
+		sub.updates <- angulardetectorsprovider.DetectorsUpdated{Provider: angulardetectorsprovider.ProviderFile, UpdatedAt: time.Now()}
+		require.Eventually(t, func() bool {
+			return !external.IsAngularDetected()
+		}, time.Second*5, time.Millisecond*10)
+	})
+}
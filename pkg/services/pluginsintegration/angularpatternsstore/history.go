@@ -0,0 +1,236 @@
+package angularpatternsstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+)
+
+const (
+	// keyHistoryEntryPrefix is the prefix of the keys of the audit history entries, which are followed by the
+	// version of the entry. Each entry is stored in its own key, which is never modified once written.
+	keyHistoryEntryPrefix = "history_entry:"
+
+	// maxHistoryEntries is the maximum number of entries kept in the audit history.
+	// Entries only contain the differences between versions, so the history can be longer than the versions one.
+	maxHistoryEntries = 100
+)
+
+// historyStore is the key-value storage of the audit history entries, which are listed by key prefix.
+type historyStore interface {
+	kvStore
+	Keys(ctx context.Context, keyPrefix string) ([]kvstore.Key, error)
+}
+
+// HistoryEntry is an entry of the audit history of the cached angular detection patterns.
+// An entry is recorded every time the patterns are set to a payload different from the previous one.
+type HistoryEntry struct {
+	// Version is the sequential number of the entry, starting from 1.
+	Version int `json:"version"`
+
+	// Hash is the hash of the patterns set by the entry.
+	Hash string `json:"hash"`
+
+	// PreviousHash is the hash of the patterns replaced by the entry, or empty for the first entry.
+	PreviousHash string `json:"previousHash,omitempty"`
+
+	// CreatedAt is the time when the patterns have been set.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Diff contains the differences between the replaced patterns and the new ones.
+	Diff PatternsDiff `json:"diff"`
+
+	// Rollback is true if the patterns have been set by rolling back to a stored version.
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+// PatternsDiff contains the differences between two versions of the angular detection patterns.
+// Patterns are matched by name. Patterns without a name are matched by their whole content.
+type PatternsDiff struct {
+	// Added contains the JSON-encoded patterns that are only present in the new version.
+	Added []json.RawMessage `json:"added"`
+
+	// Removed contains the JSON-encoded patterns that are only present in the old version.
+	Removed []json.RawMessage `json:"removed"`
+
+	// Changed contains the patterns that are present in both versions, but with a different content.
+	Changed []PatternChange `json:"changed"`
+}
+
+// PatternChange is a pattern that has been modified between two versions of the angular detection patterns.
+type PatternChange struct {
+	// Name is the name of the pattern.
+	Name string `json:"name"`
+
+	// Before is the JSON-encoded pattern in the old version.
+	Before json.RawMessage `json:"before"`
+
+	// After is the JSON-encoded pattern in the new version.
+	After json.RawMessage `json:"after"`
+}
+
+// GetHistory returns the last maxHistoryEntries entries of the audit history, the most recent one first.
+// Entries that cannot be unmarshalled correctly are skipped.
+func (s *KVStoreService) GetHistory(ctx context.Context) ([]HistoryEntry, error) {
+	versions, err := s.historyVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > maxHistoryEntries {
+		versions = versions[:maxHistoryEntries]
+	}
+	history := make([]HistoryEntry, 0, len(versions))
+	for _, version := range versions {
+		v, ok, err := s.history.Get(ctx, historyEntryKey(version))
+		if err != nil {
+			return nil, fmt.Errorf("kv get: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(v), &entry); err != nil {
+			// Ignore decode errors, so we can change the format in future versions
+			// and keep backwards/forwards compatibility
+			continue
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// historyVersions returns the versions of the stored audit history entries, the most recent one first.
+func (s *KVStoreService) historyVersions(ctx context.Context) ([]int, error) {
+	keys, err := s.history.Keys(ctx, keyHistoryEntryPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("kv keys: %w", err)
+	}
+	versions := make([]int, 0, len(keys))
+	for _, k := range keys {
+		version, err := strconv.Atoi(strings.TrimPrefix(k.Key, keyHistoryEntryPrefix))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	return versions, nil
+}
+
+// historyEntryKey returns the key of the audit history entry with the provided version.
+func historyEntryKey(version int) string {
+	return keyHistoryEntryPrefix + strconv.Itoa(version)
+}
+
+// addHistoryEntry adds an entry to the audit history for the provided JSON-encoded patterns, which replace the
+// previous ones (nil if there are none). The entry is stored in a new key, and the entries older than the last
+// maxHistoryEntries ones are deleted.
+// The callers must make sure that the history is not changed concurrently by other instances.
+func (s *KVStoreService) addHistoryEntry(ctx context.Context, previous, patterns []byte, createdAt time.Time, rollback bool) error {
+	versions, err := s.historyVersions(ctx)
+	if err != nil {
+		return err
+	}
+	entry := HistoryEntry{
+		Version:   1,
+		Hash:      PatternsHash(patterns),
+		CreatedAt: createdAt,
+		Diff:      DiffPatterns(previous, patterns),
+		Rollback:  rollback,
+	}
+	if previous != nil {
+		entry.PreviousHash = PatternsHash(previous)
+	}
+	if len(versions) > 0 {
+		entry.Version = versions[0] + 1
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	if err := s.history.Set(ctx, historyEntryKey(entry.Version), string(b)); err != nil {
+		return fmt.Errorf("kv set: %w", err)
+	}
+	if len(versions) >= maxHistoryEntries {
+		for _, version := range versions[maxHistoryEntries-1:] {
+			if err := s.history.Del(ctx, historyEntryKey(version)); err != nil {
+				return fmt.Errorf("kv del: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// DiffPatterns returns the differences between the provided JSON-encoded lists of patterns.
+// A value that is not a JSON list is considered empty, so all the patterns of the other value are reported as
+// added or removed.
+func DiffPatterns(old, updated []byte) PatternsDiff {
+	oldPatterns, oldKeys := indexPatterns(old)
+	newPatterns, newKeys := indexPatterns(updated)
+	diff := PatternsDiff{Added: []json.RawMessage{}, Removed: []json.RawMessage{}, Changed: []PatternChange{}}
+	for _, k := range newKeys {
+		after := newPatterns[k]
+		before, ok := oldPatterns[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, after)
+		case !jsonEqual(before, after):
+			diff.Changed = append(diff.Changed, PatternChange{Name: k, Before: before, After: after})
+		}
+	}
+	for _, k := range oldKeys {
+		if _, ok := newPatterns[k]; !ok {
+			diff.Removed = append(diff.Removed, oldPatterns[k])
+		}
+	}
+	return diff
+}
+
+// indexPatterns returns the provided JSON-encoded list of patterns by key, and the keys in their original order.
+// The key of a pattern is its name, or its whole content if the pattern has no name.
+func indexPatterns(b []byte) (map[string]json.RawMessage, []string) {
+	var patterns []json.RawMessage
+	if len(b) > 0 {
+		// Ignore decode errors, the patterns are considered empty
+		_ = json.Unmarshal(b, &patterns)
+	}
+	byKey := make(map[string]json.RawMessage, len(patterns))
+	keys := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		var named struct {
+			Name string `json:"name"`
+		}
+		key := string(p)
+		if err := json.Unmarshal(p, &named); err == nil && named.Name != "" {
+			key = named.Name
+		}
+		if _, ok := byKey[key]; ok {
+			// Only keep the first pattern with the same key
+			continue
+		}
+		byKey[key] = p
+		keys = append(keys, key)
+	}
+	return byKey, keys
+}
+
+// jsonEqual returns true if the provided JSON values are semantically equal, regardless of whitespace and
+// object keys order.
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return string(a) == string(b)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return string(ca) == string(cb)
+}
@@ -0,0 +1,56 @@
+package angularpatternsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+)
+
+const (
+	// remoteCacheKeyPrefix is the prefix of the remote cache keys, used to avoid collisions with other services.
+	remoteCacheKeyPrefix = kvNamespace + ":"
+
+	// remoteCacheExpiration is the expiration of the remote cache items.
+	// Items are refreshed by the background service way more often, so they expire only if Grafana is not running.
+	remoteCacheExpiration = time.Hour * 24 * 30
+)
+
+// remoteCacheKV is a kvStore that stores the values in the remote cache, so they are shared by all the instances
+// of a high availability setup.
+type remoteCacheKV struct {
+	cache remotecache.CacheStorage
+}
+
+// ProvideRemoteCacheService returns a Service that caches the angular patterns in the remote cache.
+// The audit history is stored in the database, as the remote cache items expire.
+func ProvideRemoteCacheService(cache remotecache.CacheStorage, kv kvstore.KVStore) Service {
+	return &KVStoreService{
+		kv:      remoteCacheKV{cache: cache},
+		history: kvstore.WithNamespace(kv, 0, kvNamespace),
+	}
+}
+
+func (kv remoteCacheKV) Get(ctx context.Context, key string) (string, bool, error) {
+	v, err := kv.cache.Get(ctx, remoteCacheKeyPrefix+key)
+	// The redis backend returns redis.Nil rather than remotecache.ErrCacheItemNotFound for missing keys
+	if errors.Is(err, remotecache.ErrCacheItemNotFound) || errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(v), true, nil
+}
+
+func (kv remoteCacheKV) Set(ctx context.Context, key string, value string) error {
+	return kv.cache.Set(ctx, remoteCacheKeyPrefix+key, []byte(value), remoteCacheExpiration)
+}
+
+func (kv remoteCacheKV) Del(ctx context.Context, key string) error {
+	return kv.cache.Delete(ctx, remoteCacheKeyPrefix+key)
+}
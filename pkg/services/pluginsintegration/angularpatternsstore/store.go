@@ -2,17 +2,35 @@ package angularpatternsstore
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/remoterules"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 type Service interface {
 	Get(ctx context.Context) (string, bool, error)
 	Set(ctx context.Context, patterns any) error
+	SetWithSchemaVersion(ctx context.Context, patterns any, schemaVersion int) error
+	GetSchemaVersion(ctx context.Context) (int, error)
 	GetLastUpdated(ctx context.Context) (time.Time, error)
+	SetLastUpdated(ctx context.Context) error
+	GetCacheValidators(ctx context.Context) (CacheValidators, error)
+	SetCacheValidators(ctx context.Context, validators CacheValidators) error
+	GetVersions(ctx context.Context) ([]PatternsVersion, error)
+	GetHistory(ctx context.Context) ([]HistoryEntry, error)
+	Rollback(ctx context.Context, hash string) error
+	GetPin(ctx context.Context) (Pin, bool, error)
+	SetPin(ctx context.Context, pin Pin) error
+	DeletePin(ctx context.Context) error
 }
 
 const (
@@ -20,16 +38,86 @@ const (
 
 	keyPatterns    = "angular_patterns"
 	keyLastUpdated = "last_updated"
+	keyVersions    = "versions"
+	keyPin         = "pin"
+	keyValidators  = "cache_validators"
+
+	keySchemaVersion = "schema_version"
+
+	// maxVersions is the maximum number of versions kept in the history.
+	maxVersions = 10
 )
 
-// KVStoreService allows to cache GCOM angular patterns into the database, as a cache.
+// ErrVersionNotFound is returned when a version with the provided hash does not exist.
+var ErrVersionNotFound = errors.New("version not found")
+
+// PatternsVersion is a version of the cached angular detection patterns.
+type PatternsVersion struct {
+	// Hash is the hex-encoded sha256 hash of the JSON-encoded patterns, which identifies the version.
+	Hash string `json:"hash"`
+
+	// CreatedAt is the time when the version has been stored.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Patterns contains the JSON-encoded patterns.
+	Patterns json.RawMessage `json:"patterns"`
+
+	// SchemaVersion is the schema version of the patterns.
+	// It is zero if the version has been stored without a schema version.
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+}
+
+// Pin is a patterns version that should not be replaced by the background refresh.
+type Pin struct {
+	// Hash is the hash of the pinned version.
+	Hash string `json:"hash"`
+
+	// ReplacedHash is the hash of the latest version when the pin was created.
+	ReplacedHash string `json:"replacedHash"`
+
+	// CreatedAt is the time when the pin has been created.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CacheValidators contains the HTTP cache validators returned alongside the cached patterns,
+// which can be used to make conditional requests.
+type CacheValidators = remoterules.CacheValidators
+
+// PatternsHash returns the hex-encoded sha256 hash of the provided JSON-encoded patterns.
+func PatternsHash(patterns []byte) string {
+	h := sha256.Sum256(patterns)
+	return hex.EncodeToString(h[:])
+}
+
+// kvStore is the key-value storage used by KVStoreService.
+type kvStore interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key string, value string) error
+	Del(ctx context.Context, key string) error
+}
+
+// KVStoreService allows to cache GCOM angular patterns into a key-value storage, as a cache.
+// The storage is either the database or the remote cache. The audit history is always stored in the database, so it
+// does not expire.
 type KVStoreService struct {
-	kv *kvstore.NamespacedKVStore
+	kv      kvStore
+	history historyStore
 }
 
+// ProvideStore returns a Service that uses the storage configured via angular_patterns_cache_backend.
+func ProvideStore(cfg *setting.Cfg, kv kvstore.KVStore, cache remotecache.CacheStorage) Service {
+	if cfg.AngularDetection.CacheBackend == setting.AngularPatternsCacheBackendRemoteCache {
+		return ProvideRemoteCacheService(cache, kv)
+	}
+	return ProvideService(kv)
+}
+
+// ProvideService returns a Service that caches the angular patterns in the database.
 func ProvideService(kv kvstore.KVStore) Service {
+	namespaced := kvstore.WithNamespace(kv, 0, kvNamespace)
 	return &KVStoreService{
-		kv: kvstore.WithNamespace(kv, 0, kvNamespace),
+		kv:      namespaced,
+		history: namespaced,
 	}
 }
 
@@ -39,17 +127,87 @@ func (s *KVStoreService) Get(ctx context.Context) (string, bool, error) {
 	return s.kv.Get(ctx, keyPatterns)
 }
 
-// Set sets the cached angular detection patterns and the latest update time to time.Now().
+// Set sets the cached angular detection patterns and the latest update time to time.Now(), without a schema
+// version. See SetWithSchemaVersion.
 // patterns must implement json.Marshaler.
 func (s *KVStoreService) Set(ctx context.Context, patterns any) error {
+	return s.SetWithSchemaVersion(ctx, patterns, 0)
+}
+
+// SetWithSchemaVersion sets the cached angular detection patterns, their schema version and the latest update time
+// to time.Now().
+// It also adds the patterns to the versions history, unless they are the same as the latest version, and records an
+// entry in the audit history, unless they are the same as the active patterns.
+// patterns must implement json.Marshaler.
+func (s *KVStoreService) SetWithSchemaVersion(ctx context.Context, patterns any, schemaVersion int) error {
 	b, err := json.Marshal(patterns)
 	if err != nil {
 		return fmt.Errorf("json marshal: %w", err)
 	}
+	active, hasActive, err := s.kv.Get(ctx, keyPatterns)
+	if err != nil {
+		return fmt.Errorf("kv get: %w", err)
+	}
 	if err := s.kv.Set(ctx, keyPatterns, string(b)); err != nil {
 		return fmt.Errorf("kv set: %w", err)
 	}
-	if err := s.kv.Set(ctx, keyLastUpdated, time.Now().Format(time.RFC3339)); err != nil {
+	if err := s.setSchemaVersion(ctx, schemaVersion); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := s.setLastUpdated(ctx, now); err != nil {
+		return err
+	}
+	if err := s.addVersion(ctx, b, schemaVersion, now); err != nil {
+		return fmt.Errorf("add version: %w", err)
+	}
+	if hasActive && active == string(b) {
+		return nil
+	}
+	var previous []byte
+	if hasActive {
+		previous = []byte(active)
+	}
+	if err := s.addHistoryEntry(ctx, previous, b, now, false); err != nil {
+		return fmt.Errorf("add history entry: %w", err)
+	}
+	return nil
+}
+
+// GetSchemaVersion returns the schema version of the cached patterns.
+// If no value is present or it cannot be unmarshalled correctly, it returns zero.
+func (s *KVStoreService) GetSchemaVersion(ctx context.Context) (int, error) {
+	v, ok, err := s.kv.Get(ctx, keySchemaVersion)
+	if err != nil {
+		return 0, fmt.Errorf("kv get: %w", err)
+	}
+	if !ok {
+		return 0, nil
+	}
+	schemaVersion, err := strconv.Atoi(v)
+	if err != nil {
+		// Ignore decode errors, so we can change the format in future versions
+		// and keep backwards/forwards compatibility
+		return 0, nil
+	}
+	return schemaVersion, nil
+}
+
+func (s *KVStoreService) setSchemaVersion(ctx context.Context, schemaVersion int) error {
+	if err := s.kv.Set(ctx, keySchemaVersion, strconv.Itoa(schemaVersion)); err != nil {
+		return fmt.Errorf("kv schema version set: %w", err)
+	}
+	return nil
+}
+
+// SetLastUpdated sets the latest update time to time.Now(), without modifying the cached patterns.
+// It can be used when the patterns are known to be up-to-date, but have not been re-downloaded.
+func (s *KVStoreService) SetLastUpdated(ctx context.Context) error {
+	return s.setLastUpdated(ctx, time.Now())
+}
+
+func (s *KVStoreService) setLastUpdated(ctx context.Context, t time.Time) error {
+	if err := s.kv.Set(ctx, keyLastUpdated, t.Format(time.RFC3339)); err != nil {
 		return fmt.Errorf("kv last updated set: %w", err)
 	}
 	return nil
@@ -73,3 +231,153 @@ func (s *KVStoreService) GetLastUpdated(ctx context.Context) (time.Time, error)
 	}
 	return t, nil
 }
+
+// GetVersions returns the last maxVersions stored versions of the patterns, the most recent one first.
+// If the stored value cannot be unmarshalled correctly, it returns an empty slice.
+func (s *KVStoreService) GetVersions(ctx context.Context) ([]PatternsVersion, error) {
+	v, ok, err := s.kv.Get(ctx, keyVersions)
+	if err != nil {
+		return nil, fmt.Errorf("kv get: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	var versions []PatternsVersion
+	if err := json.Unmarshal([]byte(v), &versions); err != nil {
+		// Ignore decode errors, so we can change the format in future versions
+		// and keep backwards/forwards compatibility
+		return nil, nil
+	}
+	return versions, nil
+}
+
+// Rollback sets the cached angular detection patterns and their schema version to the ones of the stored version
+// with the provided hash, and records the rollback in the audit history if the active patterns change.
+// The latest update time and the versions history are not modified.
+// If there's no such version, it returns ErrVersionNotFound.
+func (s *KVStoreService) Rollback(ctx context.Context, hash string) error {
+	versions, err := s.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("get versions: %w", err)
+	}
+	for _, v := range versions {
+		if v.Hash != hash {
+			continue
+		}
+		active, hasActive, err := s.kv.Get(ctx, keyPatterns)
+		if err != nil {
+			return fmt.Errorf("kv get: %w", err)
+		}
+		if err := s.kv.Set(ctx, keyPatterns, string(v.Patterns)); err != nil {
+			return fmt.Errorf("kv set: %w", err)
+		}
+		if err := s.setSchemaVersion(ctx, v.SchemaVersion); err != nil {
+			return err
+		}
+		if hasActive && active == string(v.Patterns) {
+			return nil
+		}
+		var previous []byte
+		if hasActive {
+			previous = []byte(active)
+		}
+		if err := s.addHistoryEntry(ctx, previous, v.Patterns, time.Now(), true); err != nil {
+			return fmt.Errorf("add history entry: %w", err)
+		}
+		return nil
+	}
+	return ErrVersionNotFound
+}
+
+// addVersion adds the provided JSON-encoded patterns, with their schema version, to the versions history, keeping at
+// most maxVersions versions. If the patterns are the same as the latest version, the versions history is not modified.
+func (s *KVStoreService) addVersion(ctx context.Context, patterns []byte, schemaVersion int, createdAt time.Time) error {
+	versions, err := s.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("get versions: %w", err)
+	}
+	hash := PatternsHash(patterns)
+	if len(versions) > 0 && versions[0].Hash == hash {
+		return nil
+	}
+	versions = append([]PatternsVersion{{Hash: hash, CreatedAt: createdAt, Patterns: patterns, SchemaVersion: schemaVersion}}, versions...)
+	if len(versions) > maxVersions {
+		versions = versions[:maxVersions]
+	}
+	b, err := json.Marshal(versions)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	if err := s.kv.Set(ctx, keyVersions, string(b)); err != nil {
+		return fmt.Errorf("kv set: %w", err)
+	}
+	return nil
+}
+
+// GetPin returns the pinned patterns version.
+// If no version is pinned, the second argument is false and the returned error is nil.
+func (s *KVStoreService) GetPin(ctx context.Context) (Pin, bool, error) {
+	v, ok, err := s.kv.Get(ctx, keyPin)
+	if err != nil {
+		return Pin{}, false, fmt.Errorf("kv get: %w", err)
+	}
+	if !ok {
+		return Pin{}, false, nil
+	}
+	var pin Pin
+	if err := json.Unmarshal([]byte(v), &pin); err != nil {
+		return Pin{}, false, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return pin, true, nil
+}
+
+// SetPin pins a patterns version.
+func (s *KVStoreService) SetPin(ctx context.Context, pin Pin) error {
+	b, err := json.Marshal(pin)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	if err := s.kv.Set(ctx, keyPin, string(b)); err != nil {
+		return fmt.Errorf("kv set: %w", err)
+	}
+	return nil
+}
+
+// DeletePin releases the pinned patterns version, if any.
+func (s *KVStoreService) DeletePin(ctx context.Context) error {
+	if err := s.kv.Del(ctx, keyPin); err != nil {
+		return fmt.Errorf("kv del: %w", err)
+	}
+	return nil
+}
+
+// GetCacheValidators returns the HTTP cache validators of the cached patterns.
+// If no value is present or it cannot be unmarshalled correctly, it returns zero-value CacheValidators.
+func (s *KVStoreService) GetCacheValidators(ctx context.Context) (CacheValidators, error) {
+	v, ok, err := s.kv.Get(ctx, keyValidators)
+	if err != nil {
+		return CacheValidators{}, fmt.Errorf("kv get: %w", err)
+	}
+	if !ok {
+		return CacheValidators{}, nil
+	}
+	var validators CacheValidators
+	if err := json.Unmarshal([]byte(v), &validators); err != nil {
+		// Ignore decode errors, so we can change the format in future versions
+		// and keep backwards/forwards compatibility
+		return CacheValidators{}, nil
+	}
+	return validators, nil
+}
+
+// SetCacheValidators sets the HTTP cache validators of the cached patterns.
+func (s *KVStoreService) SetCacheValidators(ctx context.Context, validators CacheValidators) error {
+	b, err := json.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	if err := s.kv.Set(ctx, keyValidators, string(b)); err != nil {
+		return fmt.Errorf("kv set: %w", err)
+	}
+	return nil
+}
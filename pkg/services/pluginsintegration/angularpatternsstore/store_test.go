@@ -3,12 +3,16 @@ package angularpatternsstore
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestAngularPatternsStore(t *testing.T) {
@@ -40,6 +44,298 @@ func TestAngularPatternsStore(t *testing.T) {
 		})
 	})
 
+	t.Run("versions", func(t *testing.T) {
+		svc := ProvideService(kvstore.NewFakeKVStore())
+
+		t.Run("empty", func(t *testing.T) {
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Empty(t, versions)
+		})
+
+		t.Run("set adds a version", func(t *testing.T) {
+			require.NoError(t, svc.Set(context.Background(), mockPatterns))
+
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 1)
+			expV, err := json.Marshal(mockPatterns)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expV), string(versions[0].Patterns))
+			require.NotEmpty(t, versions[0].Hash)
+			require.WithinDuration(t, time.Now(), versions[0].CreatedAt, time.Second*10)
+		})
+
+		t.Run("same patterns do not add a version", func(t *testing.T) {
+			require.NoError(t, svc.Set(context.Background(), mockPatterns))
+
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 1)
+		})
+
+		t.Run("new patterns are added first", func(t *testing.T) {
+			oldVersions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+
+			require.NoError(t, svc.Set(context.Background(), mockPatterns[:1]))
+
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+			require.NotEqual(t, oldVersions[0].Hash, versions[0].Hash)
+			require.Equal(t, oldVersions[0].Hash, versions[1].Hash)
+		})
+
+		t.Run("keeps at most maxVersions versions", func(t *testing.T) {
+			svc := ProvideService(kvstore.NewFakeKVStore())
+			for i := 0; i < maxVersions+5; i++ {
+				require.NoError(t, svc.Set(context.Background(), []map[string]interface{}{
+					{"name": "PanelCtrl", "type": "contains", "pattern": fmt.Sprintf("PanelCtrl%d", i)},
+				}))
+			}
+
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, maxVersions)
+			require.Contains(t, string(versions[0].Patterns), fmt.Sprintf("PanelCtrl%d", maxVersions+4))
+		})
+
+		t.Run("rollback", func(t *testing.T) {
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+
+			require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+
+			dbV, ok, err := svc.Get(context.Background())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, string(versions[1].Patterns), dbV)
+
+			newVersions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, versions, newVersions, "rollback should not modify the versions history")
+		})
+
+		t.Run("rollback to unknown version", func(t *testing.T) {
+			err := svc.Rollback(context.Background(), "unknown")
+			require.ErrorIs(t, err, ErrVersionNotFound)
+		})
+	})
+
+	t.Run("schema version", func(t *testing.T) {
+		svc := ProvideService(kvstore.NewFakeKVStore())
+
+		t.Run("empty", func(t *testing.T) {
+			schemaVersion, err := svc.GetSchemaVersion(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, schemaVersion)
+		})
+
+		t.Run("set stores the schema version with the patterns and the version", func(t *testing.T) {
+			require.NoError(t, svc.SetWithSchemaVersion(context.Background(), mockPatterns, 2))
+
+			schemaVersion, err := svc.GetSchemaVersion(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 2, schemaVersion)
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 1)
+			require.Equal(t, 2, versions[0].SchemaVersion)
+		})
+
+		t.Run("rollback restores the schema version of the version", func(t *testing.T) {
+			require.NoError(t, svc.SetWithSchemaVersion(context.Background(), mockPatterns[:1], 3))
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+
+			require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+			schemaVersion, err := svc.GetSchemaVersion(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 2, schemaVersion)
+		})
+	})
+
+	t.Run("history", func(t *testing.T) {
+		svc := ProvideService(kvstore.NewFakeKVStore())
+
+		t.Run("empty", func(t *testing.T) {
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Empty(t, history)
+		})
+
+		t.Run("first set adds all patterns", func(t *testing.T) {
+			require.NoError(t, svc.Set(context.Background(), mockPatterns))
+
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			require.Equal(t, 1, history[0].Version)
+			require.Empty(t, history[0].PreviousHash)
+			require.Len(t, history[0].Diff.Added, 2)
+			require.Empty(t, history[0].Diff.Removed)
+			require.Empty(t, history[0].Diff.Changed)
+
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, versions[0].Hash, history[0].Hash)
+		})
+
+		t.Run("same patterns do not add an entry", func(t *testing.T) {
+			require.NoError(t, svc.Set(context.Background(), mockPatterns))
+
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+		})
+
+		t.Run("new patterns add an entry with the diff", func(t *testing.T) {
+			require.NoError(t, svc.Set(context.Background(), []map[string]interface{}{
+				{"name": "PanelCtrl", "type": "regex", "pattern": "PanelCtrl"},
+				{"name": "QueryCtrl", "type": "contains", "pattern": "QueryCtrl"},
+			}))
+
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Len(t, history, 2)
+			entry := history[0]
+			require.Equal(t, 2, entry.Version)
+			require.Equal(t, history[1].Hash, entry.PreviousHash)
+			require.WithinDuration(t, time.Now(), entry.CreatedAt, time.Second*10)
+
+			require.Len(t, entry.Diff.Added, 1)
+			require.JSONEq(t, `{"name": "QueryCtrl", "type": "contains", "pattern": "QueryCtrl"}`, string(entry.Diff.Added[0]))
+			require.Len(t, entry.Diff.Removed, 1)
+			require.JSONEq(t, `{"name": "ConfigCtrl", "type": "contains", "pattern": "ConfigCtrl"}`, string(entry.Diff.Removed[0]))
+			require.Len(t, entry.Diff.Changed, 1)
+			require.Equal(t, "PanelCtrl", entry.Diff.Changed[0].Name)
+			require.JSONEq(t, `{"name": "PanelCtrl", "type": "contains", "pattern": "PanelCtrl"}`, string(entry.Diff.Changed[0].Before))
+			require.JSONEq(t, `{"name": "PanelCtrl", "type": "regex", "pattern": "PanelCtrl"}`, string(entry.Diff.Changed[0].After))
+		})
+
+		t.Run("rollback adds an entry", func(t *testing.T) {
+			versions, err := svc.GetVersions(context.Background())
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+			require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Len(t, history, 3)
+			entry := history[0]
+			require.Equal(t, 3, entry.Version)
+			require.True(t, entry.Rollback)
+			require.Equal(t, versions[1].Hash, entry.Hash)
+			require.Equal(t, versions[0].Hash, entry.PreviousHash)
+			require.Len(t, entry.Diff.Added, 1)
+			require.JSONEq(t, `{"name": "ConfigCtrl", "type": "contains", "pattern": "ConfigCtrl"}`, string(entry.Diff.Added[0]))
+
+			t.Run("rolling back to the active patterns does not add an entry", func(t *testing.T) {
+				require.NoError(t, svc.Rollback(context.Background(), versions[1].Hash))
+				history, err := svc.GetHistory(context.Background())
+				require.NoError(t, err)
+				require.Len(t, history, 3)
+			})
+
+			t.Run("next set is diffed against the active patterns", func(t *testing.T) {
+				require.NoError(t, svc.Set(context.Background(), []map[string]interface{}{
+					{"name": "PanelCtrl", "type": "regex", "pattern": "PanelCtrl"},
+					{"name": "QueryCtrl", "type": "contains", "pattern": "QueryCtrl"},
+				}))
+
+				history, err := svc.GetHistory(context.Background())
+				require.NoError(t, err)
+				require.Len(t, history, 4)
+				entry := history[0]
+				require.False(t, entry.Rollback)
+				require.Equal(t, versions[1].Hash, entry.PreviousHash)
+				require.Equal(t, versions[0].Hash, entry.Hash)
+				require.Len(t, entry.Diff.Added, 1)
+				require.Len(t, entry.Diff.Removed, 1)
+				require.Len(t, entry.Diff.Changed, 1)
+			})
+		})
+
+		t.Run("entries are stored in their own keys", func(t *testing.T) {
+			kv := kvstore.NewFakeKVStore()
+			svc := ProvideService(kv)
+			require.NoError(t, svc.Set(context.Background(), mockPatterns))
+			require.NoError(t, svc.Set(context.Background(), mockPatterns[:1]))
+
+			keys, err := kvstore.WithNamespace(kv, 0, kvNamespace).Keys(context.Background(), keyHistoryEntryPrefix)
+			require.NoError(t, err)
+			require.Len(t, keys, 2)
+		})
+
+		t.Run("keeps at most maxHistoryEntries entries", func(t *testing.T) {
+			svc := ProvideService(kvstore.NewFakeKVStore())
+			for i := 0; i < maxHistoryEntries+5; i++ {
+				require.NoError(t, svc.Set(context.Background(), []map[string]interface{}{
+					{"name": "PanelCtrl", "type": "contains", "pattern": fmt.Sprintf("PanelCtrl%d", i)},
+				}))
+			}
+
+			history, err := svc.GetHistory(context.Background())
+			require.NoError(t, err)
+			require.Len(t, history, maxHistoryEntries)
+			require.Equal(t, maxHistoryEntries+5, history[0].Version)
+			require.Equal(t, 6, history[maxHistoryEntries-1].Version)
+
+			keys, err := svc.(*KVStoreService).history.Keys(context.Background(), keyHistoryEntryPrefix)
+			require.NoError(t, err)
+			require.Len(t, keys, maxHistoryEntries, "older entries should be deleted")
+		})
+	})
+
+	t.Run("DiffPatterns", func(t *testing.T) {
+		t.Run("same patterns in a different format", func(t *testing.T) {
+			diff := DiffPatterns(
+				[]byte(`[{"name": "PanelCtrl", "type": "contains", "pattern": "PanelCtrl"}]`),
+				[]byte(`[{"pattern":"PanelCtrl","name":"PanelCtrl","type":"contains"}]`),
+			)
+			require.Empty(t, diff.Added)
+			require.Empty(t, diff.Removed)
+			require.Empty(t, diff.Changed)
+		})
+
+		t.Run("invalid old patterns", func(t *testing.T) {
+			diff := DiffPatterns([]byte(`{`), []byte(`[{"name": "PanelCtrl"}]`))
+			require.Len(t, diff.Added, 1)
+			require.Empty(t, diff.Removed)
+		})
+	})
+
+	t.Run("pin", func(t *testing.T) {
+		svc := ProvideService(kvstore.NewFakeKVStore())
+
+		t.Run("empty", func(t *testing.T) {
+			_, ok, err := svc.GetPin(context.Background())
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+
+		t.Run("set and get", func(t *testing.T) {
+			pin := Pin{Hash: "new", ReplacedHash: "old", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+			require.NoError(t, svc.SetPin(context.Background(), pin))
+
+			dbPin, ok, err := svc.GetPin(context.Background())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, pin, dbPin)
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			require.NoError(t, svc.DeletePin(context.Background()))
+
+			_, ok, err := svc.GetPin(context.Background())
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	})
+
 	t.Run("latest update", func(t *testing.T) {
 		svc := ProvideService(kvstore.NewFakeKVStore())
 
@@ -66,5 +362,123 @@ func TestAngularPatternsStore(t *testing.T) {
 			require.NoError(t, err)
 			require.Zero(t, lastUpdated)
 		})
+
+		t.Run("set without patterns", func(t *testing.T) {
+			svc := ProvideService(kvstore.NewFakeKVStore())
+			require.NoError(t, svc.SetLastUpdated(context.Background()))
+
+			lastUpdated, err := svc.GetLastUpdated(context.Background())
+			require.NoError(t, err)
+			require.WithinDuration(t, time.Now(), lastUpdated, time.Second*10)
+
+			_, ok, err := svc.Get(context.Background())
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	})
+
+	t.Run("cache validators", func(t *testing.T) {
+		svc := ProvideService(kvstore.NewFakeKVStore())
+
+		t.Run("empty", func(t *testing.T) {
+			validators, err := svc.GetCacheValidators(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, validators)
+		})
+
+		t.Run("set and get", func(t *testing.T) {
+			exp := CacheValidators{ETag: `"abcd"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}
+			require.NoError(t, svc.SetCacheValidators(context.Background(), exp))
+
+			validators, err := svc.GetCacheValidators(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, exp, validators)
+		})
+	})
+}
+
+func TestRemoteCacheAngularPatternsStore(t *testing.T) {
+	mockPatterns := []map[string]interface{}{
+		{"name": "PanelCtrl", "type": "contains", "pattern": "PanelCtrl"},
+	}
+
+	t.Run("get set", func(t *testing.T) {
+		cache := remotecache.NewFakeCacheStorage()
+		svc := ProvideRemoteCacheService(cache, kvstore.NewFakeKVStore())
+
+		_, ok, err := svc.Get(context.Background())
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		require.NoError(t, svc.Set(context.Background(), mockPatterns))
+		expV, err := json.Marshal(mockPatterns)
+		require.NoError(t, err)
+		v, ok, err := svc.Get(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, string(expV), v)
+
+		// Values are shared by all the services using the same remote cache
+		v, ok, err = ProvideRemoteCacheService(cache, kvstore.NewFakeKVStore()).Get(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, string(expV), v)
+
+		raw, err := cache.Get(context.Background(), remoteCacheKeyPrefix+keyPatterns)
+		require.NoError(t, err)
+		require.Equal(t, expV, raw)
+	})
+
+	t.Run("history is stored in the database", func(t *testing.T) {
+		cache := remotecache.NewFakeCacheStorage()
+		kv := kvstore.NewFakeKVStore()
+		require.NoError(t, ProvideRemoteCacheService(cache, kv).Set(context.Background(), mockPatterns))
+
+		history, err := ProvideService(kv).GetHistory(context.Background())
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	})
+
+	t.Run("redis missing keys are not found", func(t *testing.T) {
+		svc := ProvideRemoteCacheService(redisNilCacheStorage{remotecache.NewFakeCacheStorage()}, kvstore.NewFakeKVStore())
+		_, ok, err := svc.Get(context.Background())
+		require.NoError(t, err)
+		require.False(t, ok)
 	})
+
+	t.Run("pin", func(t *testing.T) {
+		svc := ProvideRemoteCacheService(remotecache.NewFakeCacheStorage(), kvstore.NewFakeKVStore())
+		pin := Pin{Hash: "abcd", ReplacedHash: "efgh", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+		require.NoError(t, svc.SetPin(context.Background(), pin))
+		v, ok, err := svc.GetPin(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, pin, v)
+
+		require.NoError(t, svc.DeletePin(context.Background()))
+		_, ok, err = svc.GetPin(context.Background())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("ProvideStore uses the configured backend", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.AngularDetection.CacheBackend = setting.AngularPatternsCacheBackendDatabase
+		svc := ProvideStore(cfg, kvstore.NewFakeKVStore(), remotecache.NewFakeCacheStorage())
+		require.IsType(t, &kvstore.NamespacedKVStore{}, svc.(*KVStoreService).kv)
+
+		cfg.AngularDetection.CacheBackend = setting.AngularPatternsCacheBackendRemoteCache
+		svc = ProvideStore(cfg, kvstore.NewFakeKVStore(), remotecache.NewFakeCacheStorage())
+		require.IsType(t, remoteCacheKV{}, svc.(*KVStoreService).kv)
+	})
+}
+
+// redisNilCacheStorage is a remotecache.CacheStorage that returns redis.Nil for all the keys, like the redis backend
+// does for missing keys.
+type redisNilCacheStorage struct {
+	remotecache.CacheStorage
+}
+
+func (redisNilCacheStorage) Get(_ context.Context, _ string) ([]byte, error) {
+	return nil, redis.Nil
 }
@@ -0,0 +1,202 @@
+package angularreport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularinspector"
+)
+
+const (
+	kvNamespace = "plugin.angularreport"
+	keyReport   = "report"
+
+	// inspectTimeout is the maximum time spent inspecting a single plugin.
+	inspectTimeout = time.Second * 10
+)
+
+// Result is the Angular detection result for a single plugin.
+type Result struct {
+	// PluginID is the ID of the inspected plugin.
+	PluginID string `json:"pluginId"`
+
+	// AngularDetected is true if the plugin matches at least one angular detection pattern.
+	AngularDetected bool `json:"angularDetected"`
+
+	// MatchedPatterns contains the names of the angular detection patterns that matched the plugin.
+	MatchedPatterns []string `json:"matchedPatterns,omitempty"`
+
+	// Error contains the error that occurred while inspecting the plugin, if any.
+	Error string `json:"error,omitempty"`
+
+	// InspectedAt is the time when the plugin has been inspected.
+	InspectedAt time.Time `json:"inspectedAt"`
+}
+
+// Report contains the Angular detection results for all the installed external plugins.
+type Report struct {
+	// GeneratedAt is the time when the report has been generated.
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Results contains the results for each plugin, sorted by plugin ID.
+	Results []Result `json:"results"`
+}
+
+// matchingDetectorsInspector returns the detectors matching a plugin, named after the patterns they have been
+// created from.
+type matchingDetectorsInspector interface {
+	MatchingNamedDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error)
+}
+
+// detectorsSubscriber is a detectors provider that notifies subscribers when its detectors change.
+type detectorsSubscriber interface {
+	Subscribe(ctx context.Context) <-chan angulardetectorsprovider.DetectorsUpdated
+	IsDisabled() bool
+}
+
+// Service runs the angular detectors against all the installed external plugins and persists the results,
+// so admins can see which plugins will stop working once Angular support is removed.
+// It also provides a background service that generates the report on startup and every time the angular
+// detection patterns change.
+type Service struct {
+	log         log.Logger
+	kv          *kvstore.NamespacedKVStore
+	inspector   matchingDetectorsInspector
+	registry    registry.Service
+	subscribers []detectorsSubscriber
+
+	// mux makes sure only one report is generated at a time.
+	mux sync.Mutex
+}
+
+func ProvideService(kv kvstore.KVStore, inspector *angularinspector.Service, pluginRegistry registry.Service, dynamic *angulardetectorsprovider.Dynamic, file *angulardetectorsprovider.File) *Service {
+	return newService(kv, inspector, pluginRegistry, dynamic, file)
+}
+
+func newService(kv kvstore.KVStore, inspector matchingDetectorsInspector, pluginRegistry registry.Service, subscribers ...detectorsSubscriber) *Service {
+	return &Service{
+		log:         log.New("plugins.angular.report"),
+		kv:          kvstore.WithNamespace(kv, 0, kvNamespace),
+		inspector:   inspector,
+		registry:    pluginRegistry,
+		subscribers: subscribers,
+	}
+}
+
+// Generate inspects all the installed external plugins, persists the results and returns them.
+// Plugins that cannot be inspected are included in the report, alongside the inspection error.
+func (s *Service) Generate(ctx context.Context) (Report, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	report := Report{GeneratedAt: time.Now(), Results: []Result{}}
+	for _, p := range s.registry.Plugins(ctx) {
+		if !p.IsExternalPlugin() {
+			continue
+		}
+		report.Results = append(report.Results, s.inspect(ctx, p))
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].PluginID < report.Results[j].PluginID
+	})
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return Report{}, fmt.Errorf("json marshal: %w", err)
+	}
+	if err := s.kv.Set(ctx, keyReport, string(b)); err != nil {
+		return Report{}, fmt.Errorf("kv set: %w", err)
+	}
+	return report, nil
+}
+
+// inspect returns the Angular detection result for the provided plugin.
+func (s *Service) inspect(ctx context.Context, p *plugins.Plugin) Result {
+	r := Result{PluginID: p.ID, InspectedAt: time.Now()}
+	cctx, canc := context.WithTimeout(ctx, inspectTimeout)
+	defer canc()
+	detectors, err := s.inspector.MatchingNamedDetectors(cctx, p)
+	if err != nil {
+		s.log.Warn("Could not inspect plugin for angular", "pluginId", p.ID, "error", err)
+		r.Error = err.Error()
+		return r
+	}
+	r.AngularDetected = len(detectors) > 0
+	for _, d := range detectors {
+		r.MatchedPatterns = append(r.MatchedPatterns, d.Name)
+	}
+	return r
+}
+
+// Get returns the latest persisted report.
+// If no report has been generated yet, the second argument is false and the returned error is nil.
+func (s *Service) Get(ctx context.Context) (Report, bool, error) {
+	v, ok, err := s.kv.Get(ctx, keyReport)
+	if err != nil || !ok {
+		return Report{}, ok, err
+	}
+	var report Report
+	if err := json.Unmarshal([]byte(v), &report); err != nil {
+		return Report{}, false, fmt.Errorf("json unmarshal: %w", err)
+	}
+	return report, true, nil
+}
+
+// Run is the function implementing the background service.
+// It generates the report on startup, and again every time any of the detectors providers notifies a change.
+func (s *Service) Run(ctx context.Context) error {
+	updates := make(chan angulardetectorsprovider.DetectorsUpdated, 1)
+	for _, sub := range s.subscribers {
+		if sub.IsDisabled() {
+			continue
+		}
+		go func(ch <-chan angulardetectorsprovider.DetectorsUpdated) {
+			for ev := range ch {
+				select {
+				case updates <- ev:
+				default:
+					// A new report is already pending
+				}
+			}
+		}(sub.Subscribe(ctx))
+	}
+
+	s.log.Debug("Started background service")
+	s.generate(ctx)
+	for {
+		select {
+		case ev := <-updates:
+			s.log.Debug("Angular detectors updated, generating new report", "provider", ev.Provider, "updatedAt", ev.UpdatedAt)
+			s.generate(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// generate generates a new report and logs the outcome.
+func (s *Service) generate(ctx context.Context) {
+	st := time.Now()
+	report, err := s.Generate(ctx)
+	if err != nil {
+		s.log.Error("Could not generate angular report", "error", err)
+		return
+	}
+	var angular int
+	for _, r := range report.Results {
+		if r.AngularDetected {
+			angular++
+		}
+	}
+	s.log.Info("Generated angular report", "plugins", len(report.Results), "angularDetected", angular, "duration", time.Since(st))
+}
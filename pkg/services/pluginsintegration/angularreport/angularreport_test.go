@@ -0,0 +1,177 @@
+package angularreport
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angulardetector"
+	"github.com/grafana/grafana/pkg/plugins/manager/loader/angular/angularinspector"
+	"github.com/grafana/grafana/pkg/plugins/manager/registry"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+type fakeDetectorsSubscriber struct {
+	updates chan angulardetectorsprovider.DetectorsUpdated
+}
+
+func (s *fakeDetectorsSubscriber) Subscribe(_ context.Context) <-chan angulardetectorsprovider.DetectorsUpdated {
+	return s.updates
+}
+
+func (s *fakeDetectorsSubscriber) IsDisabled() bool {
+	return false
+}
+
+func newTestRegistry(t *testing.T) registry.Service {
+	reg := registry.NewInMemory()
+	for _, p := range []*plugins.Plugin{
+		{
+			JSONData: plugins.JSONData{ID: "react-panel"},
+			Class:    plugins.ClassExternal,
+			FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(`console.log("react")`)}),
+		},
+		{
+			JSONData: plugins.JSONData{ID: "angular-panel"},
+			Class:    plugins.ClassExternal,
+			FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(`PanelCtrl "QueryCtrl"`)}),
+		},
+		{
+			JSONData: plugins.JSONData{ID: "core-panel"},
+			Class:    plugins.ClassCore,
+			FS:       plugins.NewInMemoryFS(map[string][]byte{"module.js": []byte(`PanelCtrl`)}),
+		},
+	} {
+		require.NoError(t, reg.Add(context.Background(), p))
+	}
+	return reg
+}
+
+type fakeMatchingDetectorsInspector func(ctx context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error)
+
+func (f fakeMatchingDetectorsInspector) MatchingNamedDetectors(ctx context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error) {
+	return f(ctx, p)
+}
+
+func TestService(t *testing.T) {
+	inspector := &angularinspector.PatternsListInspector{DetectorsProvider: angularinspector.NewDefaultStaticDetectorsProvider()}
+
+	t.Run("Get returns false if no report has been generated", func(t *testing.T) {
+		svc := newService(kvstore.NewFakeKVStore(), inspector, newTestRegistry(t))
+		_, ok, err := svc.Get(context.Background())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("Generate inspects external plugins and persists the report", func(t *testing.T) {
+		kv := kvstore.NewFakeKVStore()
+		svc := newService(kv, inspector, newTestRegistry(t))
+		st := time.Now()
+		report, err := svc.Generate(context.Background())
+		require.NoError(t, err)
+		require.False(t, report.GeneratedAt.Before(st))
+		require.Len(t, report.Results, 2)
+
+		require.Equal(t, "angular-panel", report.Results[0].PluginID)
+		require.True(t, report.Results[0].AngularDetected)
+		require.Equal(t, []string{"PanelCtrl", "QueryCtrl"}, report.Results[0].MatchedPatterns)
+		require.False(t, report.Results[0].InspectedAt.Before(st))
+
+		require.Equal(t, "react-panel", report.Results[1].PluginID)
+		require.False(t, report.Results[1].AngularDetected)
+		require.Empty(t, report.Results[1].MatchedPatterns)
+
+		// The report is persisted and can be read by another instance
+		stored, ok, err := newService(kv, inspector, registry.NewInMemory()).Get(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, report.GeneratedAt.Equal(stored.GeneratedAt))
+		require.Len(t, stored.Results, len(report.Results))
+		for i, r := range stored.Results {
+			require.Equal(t, report.Results[i].PluginID, r.PluginID)
+			require.Equal(t, report.Results[i].AngularDetected, r.AngularDetected)
+			require.Equal(t, report.Results[i].MatchedPatterns, r.MatchedPatterns)
+			require.True(t, report.Results[i].InspectedAt.Equal(r.InspectedAt))
+		}
+	})
+
+	t.Run("Generate reports the names of the matching patterns", func(t *testing.T) {
+		namedInspector := &angularinspector.PatternsListInspector{DetectorsProvider: angulardetector.ChainDetectorsProvider{
+			angulardetectorsprovider.ProvideFile(&setting.Cfg{ProvisioningPath: newTestPatternsDir(t, `[
+				{"name": "PanelCtrlPattern", "type": "contains", "pattern": "PanelCtrl"}
+			]`)}),
+		}}
+		svc := newService(kvstore.NewFakeKVStore(), namedInspector, newTestRegistry(t))
+		report, err := svc.Generate(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "angular-panel", report.Results[0].PluginID)
+		require.Equal(t, []string{"PanelCtrlPattern"}, report.Results[0].MatchedPatterns)
+	})
+
+	t.Run("Generate includes inspection errors", func(t *testing.T) {
+		svc := newService(kvstore.NewFakeKVStore(), fakeMatchingDetectorsInspector(func(_ context.Context, _ *plugins.Plugin) ([]angulardetector.NamedDetector, error) {
+			return nil, errors.New("boom")
+		}), newTestRegistry(t))
+		report, err := svc.Generate(context.Background())
+		require.NoError(t, err)
+		require.Len(t, report.Results, 2)
+		for _, r := range report.Results {
+			require.False(t, r.AngularDetected)
+			require.Equal(t, "boom", r.Error)
+		}
+	})
+
+	t.Run("Run generates the report on start and when detectors change", func(t *testing.T) {
+		var angular atomic.Bool
+		calls := make(chan struct{}, 10)
+		svc := newService(kvstore.NewFakeKVStore(), fakeMatchingDetectorsInspector(func(_ context.Context, p *plugins.Plugin) ([]angulardetector.NamedDetector, error) {
+			if p.ID == "react-panel" {
+				calls <- struct{}{}
+			}
+			if angular.Load() {
+				return []angulardetector.NamedDetector{{Name: "React", Detector: &angulardetector.ContainsBytesDetector{Pattern: []byte("react")}}}, nil
+			}
+			return nil, nil
+		}), newTestRegistry(t), &fakeDetectorsSubscriber{updates: make(chan angulardetectorsprovider.DetectorsUpdated)})
+		sub := svc.subscribers[0].(*fakeDetectorsSubscriber)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- svc.Run(ctx)
+		}()
+
+		// First report is generated on start
+		<-calls
+		angular.Store(true)
+		sub.updates <- angulardetectorsprovider.DetectorsUpdated{Provider: angulardetectorsprovider.ProviderDynamic, UpdatedAt: time.Now()}
+		<-calls
+
+		// Wait for the background service to exit, so the latest report has been persisted
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+		report, ok, err := svc.Get(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Len(t, report.Results, 2)
+		require.True(t, report.Results[1].AngularDetected)
+	})
+}
+
+// newTestPatternsDir returns a provisioning path containing an angular patterns file with the provided content.
+func newTestPatternsDir(t *testing.T, patterns string) string {
+	provisioningPath := t.TempDir()
+	dir := filepath.Join(provisioningPath, "angular-patterns")
+	require.NoError(t, os.Mkdir(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "patterns.json"), []byte(patterns), 0600))
+	return provisioningPath
+}
@@ -45,6 +45,8 @@ func ProvideConfig(settingProvider setting.Provider, grafanaCfg *setting.Cfg, fe
 		featuremgmt.ProvideToggles(features),
 		grafanaCfg.AngularSupportEnabled,
 		grafanaCfg.GrafanaComURL,
+		grafanaCfg.AngularDetection,
+		grafanaCfg.GCOMClient,
 	), nil
 }
 
@@ -0,0 +1,160 @@
+package gcomclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrRateLimited is returned when a request is not sent to GCOM because the request budget of this instance is
+// exhausted.
+var ErrRateLimited = errors.New("gcom request budget exhausted")
+
+// maxRateLimitWait is the maximum time a request without a deadline waits for the request budget to be available.
+const maxRateLimitWait = time.Second * 30
+
+// Client is the HTTP client shared by all the services calling the GCOM API, such as the plugin repository and the
+// dynamic angular detection patterns.
+// All the requests go through the same transport and are sent with the same User-Agent and telemetry headers.
+// Each caller has its own per-instance request budget, so a caller exhausting its budget (such as the angular
+// patterns fetcher) does not prevent the other callers (such as the plugin installs) from calling GCOM.
+type Client struct {
+	log            log.Logger
+	transport      http.RoundTripper
+	grafanaVersion string
+	metrics        *metrics
+
+	// limit and burst define the request budget of each caller.
+	limit rate.Limit
+	burst int
+
+	// limiters contains the rate limiters of the callers, keyed by caller.
+	// limitersMux should be acquired before reading from/writing to this field.
+	limiters    map[string]*rate.Limiter
+	limitersMux sync.Mutex
+}
+
+func ProvideClient(cfg *config.Cfg, registerer prometheus.Registerer) (*Client, error) {
+	transport, err := NewTransport(cfg.GCOMClient)
+	if err != nil {
+		return nil, fmt.Errorf("new transport: %w", err)
+	}
+	return NewClient(transport, cfg.GCOMClient, cfg.BuildVersion, registerer), nil
+}
+
+// NewClient returns a new Client that sends the requests through the provided transport, with the per-caller request
+// budget defined by the provided settings. The other settings are ignored, as they only affect the transport.
+// Metrics are not registered if registerer is nil.
+func NewClient(transport http.RoundTripper, settings setting.GCOMClientSettings, grafanaVersion string, registerer prometheus.Registerer) *Client {
+	limit, burst := rate.Inf, settings.RequestBurst
+	if settings.RequestsPerMinute > 0 {
+		limit = rate.Limit(float64(settings.RequestsPerMinute) / time.Minute.Seconds())
+		if burst < 1 {
+			// A zero burst would not allow any request
+			burst = 1
+		}
+	}
+	return &Client{
+		log:            log.New("plugins.gcom.client"),
+		transport:      transport,
+		grafanaVersion: grafanaVersion,
+		metrics:        newMetrics(registerer),
+		limit:          limit,
+		burst:          burst,
+		limiters:       map[string]*rate.Limiter{},
+	}
+}
+
+// HTTPClient returns an http.Client that sends the requests through the shared transport.
+// The caller identifies the service sending the requests in the metrics.
+func (c *Client) HTTPClient(caller string, timeout time.Duration) http.Client {
+	return http.Client{Timeout: timeout, Transport: c.Transport(caller)}
+}
+
+// Transport returns an http.RoundTripper that sends the requests through the shared transport.
+// The caller identifies the service sending the requests in the metrics, and the request budget they use: all the
+// transports returned for the same caller share the same request budget.
+func (c *Client) Transport(caller string) http.RoundTripper {
+	return &roundTripper{client: c, caller: caller, limiter: c.limiter(caller)}
+}
+
+// limiter returns the rate limiter of the provided caller, creating it if needed.
+func (c *Client) limiter(caller string) *rate.Limiter {
+	c.limitersMux.Lock()
+	defer c.limitersMux.Unlock()
+	l, ok := c.limiters[caller]
+	if !ok {
+		l = rate.NewLimiter(c.limit, c.burst)
+		c.limiters[caller] = l
+	}
+	return l
+}
+
+// roundTripper is the http.RoundTripper returned by Client.Transport.
+type roundTripper struct {
+	client  *Client
+	caller  string
+	limiter *rate.Limiter
+}
+
+// RoundTrip waits for the request budget of the caller to be available, sets the common headers and sends the request through
+// the shared transport. If the request budget is not available before the request deadline (or maxRateLimitWait,
+// if the request has no deadline), ErrRateLimited is returned.
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := rt.client
+	if err := wait(req.Context(), rt.limiter); err != nil {
+		c.metrics.rateLimited.WithLabelValues(rt.caller).Inc()
+		c.log.Warn("GCOM request budget exhausted, request not sent", "caller", rt.caller, "url", req.URL.String())
+		return nil, fmt.Errorf("%w: %s", ErrRateLimited, err)
+	}
+
+	// Do not modify the original request, as required by http.RoundTripper
+	req = req.Clone(req.Context())
+	if c.grafanaVersion != "" {
+		setDefaultHeader(req, "User-Agent", "grafana "+c.grafanaVersion)
+		setDefaultHeader(req, "grafana-version", c.grafanaVersion)
+	}
+	setDefaultHeader(req, "grafana-os", runtime.GOOS)
+	setDefaultHeader(req, "grafana-arch", runtime.GOARCH)
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		c.metrics.requests.WithLabelValues(rt.caller, "error").Inc()
+		return nil, err
+	}
+	c.metrics.requests.WithLabelValues(rt.caller, strconv.Itoa(resp.StatusCode)).Inc()
+	return resp, nil
+}
+
+// wait blocks until the request budget of the provided limiter is available, or returns an error if it cannot be
+// available before the context deadline or maxRateLimitWait, whichever comes first.
+func wait(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter.Limit() == rate.Inf {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var canc context.CancelFunc
+		ctx, canc = context.WithTimeout(ctx, maxRateLimitWait)
+		defer canc()
+	}
+	return limiter.Wait(ctx)
+}
+
+// setDefaultHeader sets the provided header, unless it has already been set by the caller.
+func setDefaultHeader(req *http.Request, key, value string) {
+	if req.Header.Get(key) == "" {
+		req.Header.Set(key, value)
+	}
+}
@@ -0,0 +1,90 @@
+package gcomclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestClient(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	get := func(t *testing.T, c http.Client, ctx context.Context, header http.Header) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+		require.NoError(t, resp.Body.Close())
+		return nil
+	}
+
+	t.Run("sets common headers", func(t *testing.T) {
+		c := NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "10.2.0", nil)
+		require.NoError(t, get(t, c.HTTPClient("test", time.Second), context.Background(), nil))
+		require.Equal(t, "grafana 10.2.0", headers.Get("User-Agent"))
+		require.Equal(t, "10.2.0", headers.Get("grafana-version"))
+		require.Equal(t, runtime.GOOS, headers.Get("grafana-os"))
+		require.Equal(t, runtime.GOARCH, headers.Get("grafana-arch"))
+	})
+
+	t.Run("does not override headers set by the caller", func(t *testing.T) {
+		c := NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "10.2.0", nil)
+		require.NoError(t, get(t, c.HTTPClient("test", time.Second), context.Background(), http.Header{
+			"User-Agent":      []string{"custom"},
+			"Grafana-Version": []string{"10.1.0"},
+		}))
+		require.Equal(t, "custom", headers.Get("User-Agent"))
+		require.Equal(t, "10.1.0", headers.Get("grafana-version"))
+	})
+
+	t.Run("no limit by default", func(t *testing.T) {
+		c := NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, get(t, c.HTTPClient("test", time.Second), context.Background(), nil))
+		}
+	})
+
+	t.Run("each caller has its own request budget", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		c := NewClient(http.DefaultTransport, setting.GCOMClientSettings{RequestsPerMinute: 1, RequestBurst: 2}, "", reg)
+		ctx, canc := context.WithTimeout(context.Background(), time.Millisecond*100)
+		t.Cleanup(canc)
+
+		// The budget is shared by the clients of the same caller
+		require.NoError(t, get(t, c.HTTPClient("a", 0), ctx, nil))
+		require.NoError(t, get(t, c.HTTPClient("a", 0), ctx, nil))
+		err := get(t, c.HTTPClient("a", 0), ctx, nil)
+		require.ErrorIs(t, err, ErrRateLimited)
+
+		// Other callers are not affected
+		require.NoError(t, get(t, c.HTTPClient("b", 0), ctx, nil))
+
+		require.Equal(t, 2.0, testutil.ToFloat64(c.metrics.requests.WithLabelValues("a", "200")))
+		require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.requests.WithLabelValues("b", "200")))
+		require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.rateLimited.WithLabelValues("a")))
+	})
+
+	t.Run("zero burst allows one request", func(t *testing.T) {
+		c := NewClient(http.DefaultTransport, setting.GCOMClientSettings{RequestsPerMinute: 1}, "", nil)
+		require.NoError(t, get(t, c.HTTPClient("test", time.Second), context.Background(), nil))
+	})
+}
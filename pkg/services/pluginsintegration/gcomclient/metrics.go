@@ -0,0 +1,41 @@
+package gcomclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubSystem = "plugins"
+)
+
+type metrics struct {
+	requests    *prometheus.CounterVec
+	rateLimited *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "gcom_requests_total",
+			Help:      "Number of requests sent to GCOM, by caller and HTTP status code",
+		}, []string{"caller", "status_code"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "gcom_requests_rate_limited_total",
+			Help:      "Number of requests not sent to GCOM because the request budget was exhausted, by caller",
+		}, []string{"caller"}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.requests,
+			m.rateLimited,
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,89 @@
+package gcomclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// NewTransport returns the HTTP transport used to call GCOM, configured with the provided settings.
+// Same configuration as pkg/plugins/repo/client.go, plus custom CA, client certificate and proxy.
+func NewTransport(settings setting.GCOMClientSettings) (*http.Transport, error) {
+	tlsConfig, err := makeTLSConfig(settings)
+	if err != nil {
+		return nil, fmt.Errorf("tls config: %w", err)
+	}
+	proxy, err := makeProxy(settings)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// makeTLSConfig returns the TLS configuration with the custom CA bundle and client certificate, if any.
+// It returns nil if neither are configured, so the default TLS configuration is used.
+func makeTLSConfig(settings setting.GCOMClientSettings) (*tls.Config, error) {
+	if settings.CACertPath == "" && settings.ClientCertPath == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if settings.CACertPath != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		// nolint:gosec
+		// We can ignore the gosec G304 warning since the path comes from the Grafana configuration
+		caCert, err := os.ReadFile(settings.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("read ca cert: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("no valid certificates found in ca cert file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if settings.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(settings.ClientCertPath, settings.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// makeProxy returns the proxy function of the transport.
+// If no proxy URL is configured, the proxy is read from the environment.
+func makeProxy(settings setting.GCOMClientSettings) (func(*http.Request) (*url.URL, error), error) {
+	if settings.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(settings.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url: %w", err)
+	}
+	if settings.ProxyUsername != "" {
+		proxyURL.User = url.UserPassword(settings.ProxyUsername, settings.ProxyPassword)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
@@ -0,0 +1,184 @@
+package gcomclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestNewTransport(t *testing.T) {
+	t.Run("default settings", func(t *testing.T) {
+		tr, err := NewTransport(setting.GCOMClientSettings{})
+		require.NoError(t, err)
+		require.Nil(t, tr.TLSClientConfig)
+	})
+
+	t.Run("custom ca and client certificate", func(t *testing.T) {
+		dir := t.TempDir()
+		ca, caKey := newTestCertificate(t, nil, nil, "ca")
+		serverCert, serverKey := newTestCertificate(t, ca, caKey, "server")
+		clientCert, clientKey := newTestCertificate(t, ca, caKey, "client")
+		caPath := writeTestPEM(t, dir, "ca.pem", ca, nil)
+		clientCertPath := writeTestPEM(t, dir, "client.pem", clientCert, nil)
+		clientKeyPath := writeTestPEM(t, dir, "client.key", nil, clientKey)
+
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca)
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		srv.TLS = &tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		}
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+
+		for _, tc := range []struct {
+			name     string
+			settings setting.GCOMClientSettings
+			expError bool
+		}{
+			{
+				name:     "succeeds with ca and client certificate",
+				settings: setting.GCOMClientSettings{CACertPath: caPath, ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath},
+			},
+			{
+				name:     "fails without client certificate",
+				settings: setting.GCOMClientSettings{CACertPath: caPath},
+				expError: true,
+			},
+			{
+				name:     "fails without ca",
+				settings: setting.GCOMClientSettings{ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath},
+				expError: true,
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				tr, err := NewTransport(tc.settings)
+				require.NoError(t, err)
+				c := http.Client{Transport: tr}
+				resp, err := c.Get(srv.URL)
+				if tc.expError {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			})
+		}
+	})
+
+	t.Run("invalid ca file", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(fn, []byte("not a certificate"), 0600))
+		_, err := NewTransport(setting.GCOMClientSettings{CACertPath: fn})
+		require.Error(t, err)
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		_, err := NewTransport(setting.GCOMClientSettings{ClientCertPath: "/does/not/exist.pem", ClientKeyPath: "/does/not/exist.key"})
+		require.Error(t, err)
+	})
+
+	t.Run("authenticated proxy", func(t *testing.T) {
+		expAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:password"))
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Proxy-Authorization") != expAuth {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			require.Equal(t, "gcom.invalid", r.URL.Host)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(proxy.Close)
+
+		for _, tc := range []struct {
+			name          string
+			settings      setting.GCOMClientSettings
+			expStatusCode int
+		}{
+			{
+				name:          "with credentials",
+				settings:      setting.GCOMClientSettings{ProxyURL: proxy.URL, ProxyUsername: "user", ProxyPassword: "password"},
+				expStatusCode: http.StatusOK,
+			},
+			{
+				name:          "without credentials",
+				settings:      setting.GCOMClientSettings{ProxyURL: proxy.URL},
+				expStatusCode: http.StatusProxyAuthRequired,
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				tr, err := NewTransport(tc.settings)
+				require.NoError(t, err)
+				c := http.Client{Transport: tr}
+				resp, err := c.Get("http://gcom.invalid/api/plugins/angular_patterns")
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+				require.Equal(t, tc.expStatusCode, resp.StatusCode)
+			})
+		}
+	})
+}
+
+// newTestCertificate returns a new certificate and its private key, signed by the provided parent.
+// If parent is nil, a self-signed CA certificate is returned.
+func newTestCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, cn string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writeTestPEM writes the provided certificate or private key to a PEM-encoded file and returns its path.
+func writeTestPEM(t *testing.T, dir, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) string {
+	var block *pem.Block
+	if cert != nil {
+		block = &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}
+	} else {
+		b, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}
+	}
+	fn := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(fn, pem.EncodeToMemory(block), 0600))
+	return fn
+}
@@ -1,6 +1,8 @@
 package pluginsintegration
 
 import (
+	"net/url"
+
 	"github.com/google/wire"
 
 	"github.com/grafana/grafana/pkg/infra/tracing"
@@ -8,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/backendplugin/coreplugin"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin/provider"
 	pCfg "github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/log"
 	"github.com/grafana/grafana/pkg/plugins/manager"
 	"github.com/grafana/grafana/pkg/plugins/manager/client"
 	"github.com/grafana/grafana/pkg/plugins/manager/filestore"
@@ -34,8 +37,10 @@ import (
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angulardetectorsprovider"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularinspector"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularpatternsstore"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/angularreport"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/clientmiddleware"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/config"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/keyretriever"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/keyretriever/dynamic"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/keystore"
@@ -77,9 +82,14 @@ var WireSet = wire.NewSet(
 	pipeline.ProvideValidationStage,
 	wire.Bind(new(validation.Validator), new(*validation.Validate)),
 
-	angularpatternsstore.ProvideService,
+	angularpatternsstore.ProvideStore,
 	angulardetectorsprovider.ProvideDynamic,
+	angulardetectorsprovider.ProvideFile,
+	angulardetectorsprovider.ProvideInstallChecker,
+	wire.Bind(new(plugins.ArchiveValidator), new(*angulardetectorsprovider.InstallChecker)),
 	angularinspector.ProvideService,
+	angularinspector.ProvideReevaluator,
+	angularreport.ProvideService,
 	wire.Bind(new(pAngularInspector.Inspector), new(*angularinspector.Service)),
 
 	signature.ProvideValidatorService,
@@ -94,7 +104,8 @@ var WireSet = wire.NewSet(
 	wire.Bind(new(plugins.Installer), new(*manager.PluginInstaller)),
 	registry.ProvideService,
 	wire.Bind(new(registry.Service), new(*registry.InMemory)),
-	repo.ProvideService,
+	gcomclient.ProvideClient,
+	ProvideRepoService,
 	wire.Bind(new(repo.Service), new(*repo.Manager)),
 	plugincontext.ProvideService,
 	licensing.ProvideLicensing,
@@ -173,3 +184,18 @@ func CreateMiddlewares(cfg *setting.Cfg, oAuthTokenService oauthtoken.OAuthToken
 
 	return middlewares
 }
+
+// ProvideRepoService returns the plugin repository, which calls the GCOM API through the shared GCOM client, with its
+// own request budget. The plugin archives are downloaded without the GCOM client, as they may be served by other hosts.
+func ProvideRepoService(cfg *pCfg.Cfg, gcomClient *gcomclient.Client) (*repo.Manager, error) {
+	baseURL, err := url.JoinPath(cfg.GrafanaComURL, "/api/plugins")
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.NewManager(repo.ManagerCfg{
+		BaseURL:   baseURL,
+		Logger:    log.NewPrettyLogger("plugin.repository"),
+		Transport: gcomClient.Transport("plugin_repo"),
+	}), nil
+}
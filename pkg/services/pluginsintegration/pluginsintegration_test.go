@@ -0,0 +1,78 @@
+package pluginsintegration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+	"github.com/grafana/grafana/pkg/plugins/repo"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestProvideRepoService(t *testing.T) {
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	f, err := w.Create("plugin.json")
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`{"id": "test-app"}`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/plugins/repo/test-app", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions": [{"version": "1.0.0"}]}`))
+	})
+	mux.HandleFunc("/api/plugins/test-app/versions/1.0.0/download", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	})
+	mux.HandleFunc("/api/plugins/angular_patterns", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	gcomClient := gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{RequestsPerMinute: 1, RequestBurst: 2}, "", nil)
+	m, err := ProvideRepoService(&config.Cfg{GrafanaComURL: srv.URL}, gcomClient)
+	require.NoError(t, err)
+
+	t.Run("plugins can be installed while the angular patterns budget is exhausted", func(t *testing.T) {
+		ctx, canc := context.WithTimeout(context.Background(), time.Millisecond*100)
+		t.Cleanup(canc)
+		patternsClient := gcomClient.HTTPClient("angular_patterns", 0)
+		for {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/plugins/angular_patterns", nil)
+			require.NoError(t, err)
+			resp, err := patternsClient.Do(req)
+			if err != nil {
+				require.ErrorIs(t, err, gcomclient.ErrRateLimited)
+				break
+			}
+			require.NoError(t, resp.Body.Close())
+		}
+
+		pa, err := m.GetPluginArchive(ctx, "test-app", "", repo.NewCompatOpts("10.2.0", "linux", "amd64"))
+		require.NoError(t, err)
+		require.Len(t, pa.File.File, 1)
+	})
+
+	t.Run("archives are not downloaded through the GCOM client", func(t *testing.T) {
+		ctx, canc := context.WithTimeout(context.Background(), time.Millisecond*100)
+		t.Cleanup(canc)
+		gcomClient := gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{RequestsPerMinute: 1, RequestBurst: 1}, "", nil)
+		m, err := ProvideRepoService(&config.Cfg{GrafanaComURL: srv.URL}, gcomClient)
+		require.NoError(t, err)
+
+		// The budget only allows the request to the repository API
+		pa, err := m.GetPluginArchive(ctx, "test-app", "", repo.NewCompatOpts("10.2.0", "linux", "amd64"))
+		require.NoError(t, err)
+		require.Len(t, pa.File.File, 1)
+	})
+}
@@ -0,0 +1,128 @@
+package remoterules
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/plugins/log"
+)
+
+// ErrCircuitOpen is returned when GCOM is not called because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerState is the state of a CircuitBreaker.
+type CircuitBreakerState string
+
+const (
+	// CircuitBreakerClosed means that fetches are allowed.
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+
+	// CircuitBreakerOpen means that fetches are not allowed, because too many consecutive fetches failed.
+	CircuitBreakerOpen CircuitBreakerState = "open"
+
+	// CircuitBreakerHalfOpen means that the cooldown has passed and a single trial fetch is allowed.
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+// CircuitBreaker is a simple consecutive-failures circuit breaker, which protects GCOM from repeated failing fetches.
+// After failureThreshold consecutive failures the breaker opens, and no calls are allowed until cooldown has passed.
+// After the cooldown, a single trial call is allowed (half-open): if it succeeds the breaker closes, otherwise it
+// opens again.
+type CircuitBreaker struct {
+	log log.Logger
+
+	failureThreshold int
+	cooldown         time.Duration
+
+	// onStateChange is called with the new state every time the state changes.
+	onStateChange func(CircuitBreakerState)
+
+	// Now returns the current time. It can be overwritten in tests.
+	Now func() time.Time
+
+	state               CircuitBreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	mux                 sync.Mutex
+}
+
+// NewCircuitBreaker returns a new closed CircuitBreaker, which opens after failureThreshold consecutive failures.
+// onStateChange, if not nil, is called with the new state every time the state changes.
+func NewCircuitBreaker(logger log.Logger, failureThreshold int, cooldown time.Duration, onStateChange func(CircuitBreakerState)) *CircuitBreaker {
+	return &CircuitBreaker{
+		log:              logger,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		onStateChange:    onStateChange,
+		Now:              time.Now,
+		state:            CircuitBreakerClosed,
+	}
+}
+
+// Allow returns true if a call is allowed.
+// If the breaker is open and the cooldown has passed, the breaker becomes half-open and a single call is allowed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case CircuitBreakerOpen:
+		if b.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(CircuitBreakerHalfOpen)
+		return true
+	case CircuitBreakerHalfOpen:
+		// A trial call is already in progress
+		return false
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the breaker.
+func (b *CircuitBreaker) Success() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.consecutiveFailures = 0
+	if b.state != CircuitBreakerClosed {
+		b.setState(CircuitBreakerClosed)
+	}
+}
+
+// Failure records a failed call and opens the breaker if the failure threshold has been reached,
+// or if the trial call of a half-open breaker failed.
+func (b *CircuitBreaker) Failure() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.consecutiveFailures++
+	if b.state == CircuitBreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.openedAt = b.Now()
+		if b.state != CircuitBreakerOpen {
+			b.setState(CircuitBreakerOpen)
+		}
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.state
+}
+
+// ConsecutiveFailures returns the number of consecutive failed calls since the last successful one.
+func (b *CircuitBreaker) ConsecutiveFailures() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.consecutiveFailures
+}
+
+// setState changes the state of the breaker. The caller must Lock b.mux before calling this function.
+func (b *CircuitBreaker) setState(state CircuitBreakerState) {
+	b.log.Info("Circuit breaker state changed", "from", b.state, "to", state, "consecutiveFailures", b.consecutiveFailures)
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(state)
+	}
+}
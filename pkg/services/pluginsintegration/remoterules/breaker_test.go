@@ -0,0 +1,73 @@
+package remoterules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins/log"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = time.Minute
+
+	newBreaker := func() (*CircuitBreaker, *time.Time, *[]CircuitBreakerState) {
+		var states []CircuitBreakerState
+		b := NewCircuitBreaker(log.NewTestLogger(), 2, cooldown, func(state CircuitBreakerState) {
+			states = append(states, state)
+		})
+		now := time.Now()
+		b.Now = func() time.Time { return now }
+		return b, &now, &states
+	}
+
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		b, _, states := newBreaker()
+		b.Failure()
+		require.True(t, b.Allow())
+		b.Failure()
+		require.Equal(t, CircuitBreakerOpen, b.State())
+		require.False(t, b.Allow())
+		require.Equal(t, []CircuitBreakerState{CircuitBreakerOpen}, *states)
+	})
+
+	t.Run("success resets consecutive failures", func(t *testing.T) {
+		b, _, _ := newBreaker()
+		b.Failure()
+		require.Equal(t, 1, b.ConsecutiveFailures())
+		b.Success()
+		require.Zero(t, b.ConsecutiveFailures())
+		b.Failure()
+		require.Equal(t, 1, b.ConsecutiveFailures())
+		require.Equal(t, CircuitBreakerClosed, b.State())
+		require.True(t, b.Allow())
+	})
+
+	t.Run("allows a single trial call after the cooldown", func(t *testing.T) {
+		b, now, _ := newBreaker()
+		b.Failure()
+		b.Failure()
+		*now = now.Add(cooldown)
+		require.True(t, b.Allow())
+		require.Equal(t, CircuitBreakerHalfOpen, b.State())
+		require.False(t, b.Allow())
+	})
+
+	t.Run("failed trial call opens the breaker again", func(t *testing.T) {
+		b, now, states := newBreaker()
+		b.Failure()
+		b.Failure()
+		*now = now.Add(cooldown)
+		require.True(t, b.Allow())
+		b.Failure()
+		require.Equal(t, CircuitBreakerOpen, b.State())
+		require.False(t, b.Allow())
+		require.Equal(t, []CircuitBreakerState{CircuitBreakerOpen, CircuitBreakerHalfOpen, CircuitBreakerOpen}, *states)
+
+		*now = now.Add(cooldown)
+		require.True(t, b.Allow())
+		b.Success()
+		require.Equal(t, CircuitBreakerClosed, b.State())
+	})
+}
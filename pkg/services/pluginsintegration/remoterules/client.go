@@ -0,0 +1,314 @@
+// Package remoterules fetches rules published on GCOM, such as the angular detection patterns.
+// It takes care of the HTTP requests, retries, circuit breaking, signature verification and metrics, so a new feed
+// only has to provide a FeedConfig and a Decoder for its rules, and to cache and refresh them.
+package remoterules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/plugins/log"
+	"github.com/grafana/grafana/pkg/plugins/manager/signature/statickey"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+)
+
+const (
+	// CircuitBreakerFailureThreshold is the number of consecutive failed fetches after which GCOM is not called
+	// anymore until CircuitBreakerCooldown has passed.
+	CircuitBreakerFailureThreshold = 3
+
+	// CircuitBreakerCooldown is the time that passes between the circuit breaker opening and the next trial fetch.
+	CircuitBreakerCooldown = time.Minute * 15
+
+	// gcomRequestTimeout is the timeout of each request sent to GCOM.
+	gcomRequestTimeout = time.Second * 10
+
+	// maxRulesSize is the maximum size of the rules returned by GCOM, which are way smaller (the angular detection
+	// patterns are a few KB). Bigger responses are rejected rather than read into memory.
+	maxRulesSize = 5 * 1024 * 1024
+)
+
+// DefaultFetchBackoff is the backoff configuration used to retry failed fetches from GCOM.
+var DefaultFetchBackoff = backoff.Config{
+	MinBackoff: time.Second,
+	MaxBackoff: time.Second * 10,
+	MaxRetries: 3,
+}
+
+// ErrNotModified is returned by Client.Fetch when GCOM replies that the rules have not been modified.
+var ErrNotModified = errors.New("not modified")
+
+// ErrResponseTooLarge is returned by Client.Fetch when the rules or their signature returned by GCOM are bigger
+// than the maximum allowed size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// UnexpectedStatusCodeError is returned by Client.Fetch when GCOM replies with an unexpected HTTP status code.
+type UnexpectedStatusCodeError struct {
+	StatusCode int
+}
+
+func (e UnexpectedStatusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+}
+
+// CacheValidators contains the HTTP cache validators returned alongside the rules,
+// which can be used to make conditional requests.
+type CacheValidators struct {
+	// ETag is the value of the ETag response header.
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the value of the Last-Modified response header.
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// FetchResult is the result of a successful Client.Fetch.
+type FetchResult[T any] struct {
+	// Rules contains the decoded rules.
+	Rules T
+
+	// Raw contains the raw response body the rules have been decoded from.
+	Raw []byte
+
+	// Validators contains the HTTP cache validators of the response.
+	Validators CacheValidators
+}
+
+// FeedConfig is the configuration of a feed of rules published on GCOM.
+type FeedConfig struct {
+	// Name identifies the feed. It is used as the prefix of the metrics of the feed and as the caller in the shared
+	// GCOM client metrics, so it must be a valid Prometheus metric name (e.g.: angular_patterns).
+	Name string
+
+	// Description is the human-readable name of the rules, used in the metrics help (e.g.: angular detection
+	// patterns). If empty, the name is used.
+	Description string
+
+	// Path is the relative path to the GCOM API handler that returns the rules.
+	Path string
+
+	// SignaturePath is the relative path to the GCOM API handler that returns the armored detached signature of
+	// the rules. It is only used if RequireSignature is true.
+	SignaturePath string
+
+	// RequireSignature is true if the rules must be signed with the Grafana public key.
+	RequireSignature bool
+}
+
+func (f FeedConfig) description() string {
+	if f.Description != "" {
+		return f.Description
+	}
+	return strings.ReplaceAll(f.Name, "_", " ")
+}
+
+// Decoder decodes the raw rules returned by GCOM. It is the only part that has to be implemented for a new feed.
+type Decoder[T any] interface {
+	Decode(b []byte) (T, error)
+}
+
+// DecoderFunc is a function that implements Decoder.
+type DecoderFunc[T any] func(b []byte) (T, error)
+
+// Decode calls f(b).
+func (f DecoderFunc[T]) Decode(b []byte) (T, error) {
+	return f(b)
+}
+
+// Client fetches the rules of a feed from GCOM and decodes them with the Decoder of the feed.
+// Transient errors are retried with an exponential backoff, and a circuit breaker stops calling GCOM for a while
+// after too many consecutive failed fetches.
+type Client[T any] struct {
+	log     log.Logger
+	feed    FeedConfig
+	decoder Decoder[T]
+
+	// BaseURL is the base URL of the GCOM API. It can be overwritten in tests.
+	BaseURL string
+
+	// HTTPClient is the client used to send the requests to GCOM. It can be overwritten in tests.
+	HTTPClient http.Client
+
+	// Backoff is the backoff configuration used to retry transient fetch errors within a single fetch.
+	// It can be overwritten in tests.
+	Backoff backoff.Config
+
+	// PublicKey is the armored public key used to verify the signature of the rules.
+	// It can be overwritten in tests.
+	PublicKey string
+
+	// Breaker stops calling GCOM for a while after too many consecutive failed fetches.
+	Breaker *CircuitBreaker
+
+	// Metrics are the metrics of the feed.
+	Metrics *Metrics
+}
+
+// NewClient returns a new Client for the provided feed, which sends the requests through the shared GCOM client.
+// Metrics are not registered if registerer is nil.
+func NewClient[T any](feed FeedConfig, baseURL string, gcomClient *gcomclient.Client, decoder Decoder[T], registerer prometheus.Registerer) *Client[T] {
+	c := &Client[T]{
+		log:        log.New("plugin.remoterules").New("feed", feed.Name),
+		feed:       feed,
+		decoder:    decoder,
+		BaseURL:    baseURL,
+		HTTPClient: gcomClient.HTTPClient(feed.Name, gcomRequestTimeout),
+		Backoff:    DefaultFetchBackoff,
+		PublicKey:  statickey.GetDefaultKey(),
+		Metrics:    newMetrics(registerer, feed),
+	}
+	c.Breaker = NewCircuitBreaker(c.log, CircuitBreakerFailureThreshold, CircuitBreakerCooldown, func(state CircuitBreakerState) {
+		if state == CircuitBreakerClosed {
+			c.Metrics.CircuitBreakerOpen.Set(0)
+		} else {
+			c.Metrics.CircuitBreakerOpen.Set(1)
+		}
+	})
+	return c
+}
+
+// URL returns the URL of the GCOM API handler that returns the rules of the feed.
+func (c *Client[T]) URL() (string, error) {
+	reqURL, err := url.JoinPath(c.BaseURL, c.feed.Path)
+	if err != nil {
+		return "", fmt.Errorf("url joinpath: %w", err)
+	}
+	return reqURL, nil
+}
+
+// Fetch fetches the rules from GCOM and decodes them, retrying transient errors with an exponential backoff.
+// If validators are not empty, a conditional request is made, and ErrNotModified is returned if the rules have
+// not been modified.
+// GCOM is not called if the circuit breaker is open, in which case ErrCircuitOpen is returned.
+func (c *Client[T]) Fetch(ctx context.Context, validators CacheValidators) (FetchResult[T], error) {
+	if !c.Breaker.Allow() {
+		return FetchResult[T]{}, ErrCircuitOpen
+	}
+	r, err := c.fetchWithRetry(ctx, validators)
+	if err != nil && !errors.Is(err, ErrNotModified) {
+		c.Breaker.Failure()
+		c.Metrics.FetchErrors.Inc()
+		return FetchResult[T]{}, err
+	}
+	c.Breaker.Success()
+	return r, err
+}
+
+// isRetryableFetchError returns true if the provided fetch error is transient, and the fetch should be retried.
+// Connection-level errors, 429s and 5xx responses are retried. Requests not sent because the GCOM request budget
+// is exhausted are not retried.
+func isRetryableFetchError(err error) bool {
+	if errors.Is(err, gcomclient.ErrRateLimited) {
+		return false
+	}
+	var statusErr UnexpectedStatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode/100 == 5
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// fetchWithRetry calls fetch, retrying transient errors with an exponential backoff.
+// It returns the error of the last attempt if all attempts fail.
+func (c *Client[T]) fetchWithRetry(ctx context.Context, validators CacheValidators) (FetchResult[T], error) {
+	b := backoff.New(ctx, c.Backoff)
+	for {
+		fetchStart := time.Now()
+		r, err := c.fetch(ctx, validators)
+		c.Metrics.FetchDuration.Observe(time.Since(fetchStart).Seconds())
+		if err == nil || !isRetryableFetchError(err) {
+			return r, err
+		}
+		b.Wait()
+		if !b.Ongoing() {
+			return FetchResult[T]{}, err
+		}
+		c.log.Warn("Error fetching rules, retrying", "error", err, "retry", b.NumRetries())
+		c.Metrics.FetchRetries.Inc()
+	}
+}
+
+// fetch makes a single attempt to fetch the rules from GCOM and decode them.
+func (c *Client[T]) fetch(ctx context.Context, validators CacheValidators) (FetchResult[T], error) {
+	st := time.Now()
+
+	reqURL, err := c.URL()
+	if err != nil {
+		return FetchResult[T]{}, err
+	}
+
+	c.log.Debug("Fetching rules", "url", reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return FetchResult[T]{}, fmt.Errorf("new request with context: %w", err)
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return FetchResult[T]{}, fmt.Errorf("http do: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			c.log.Error("Response body close error", "error", closeErr)
+		}
+	}()
+	c.Metrics.HTTPResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		c.log.Debug("Rules not modified", "duration", time.Since(st))
+		return FetchResult[T]{Validators: validators}, ErrNotModified
+	default:
+		return FetchResult[T]{}, UnexpectedStatusCodeError{StatusCode: resp.StatusCode}
+	}
+	body, err := readBody(resp.Body, maxRulesSize)
+	if err != nil {
+		return FetchResult[T]{}, fmt.Errorf("read body: %w", err)
+	}
+	if c.feed.RequireSignature {
+		if err := c.verifySignature(ctx, body); err != nil {
+			return FetchResult[T]{}, fmt.Errorf("verify signature: %w", err)
+		}
+	}
+	rules, err := c.decoder.Decode(body)
+	if err != nil {
+		return FetchResult[T]{}, fmt.Errorf("decode: %w", err)
+	}
+	c.log.Debug("Fetched rules", "duration", time.Since(st))
+	return FetchResult[T]{
+		Rules: rules,
+		Raw:   body,
+		Validators: CacheValidators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// readBody reads the provided response body, returning an error wrapping ErrResponseTooLarge if it is bigger than
+// limit bytes.
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return b, nil
+}
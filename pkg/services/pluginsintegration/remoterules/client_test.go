@@ -0,0 +1,170 @@
+package remoterules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/gcomclient"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testRulesPath = "/api/plugins/test_rules"
+
+var testFeed = FeedConfig{Name: "test_rules", Path: testRulesPath}
+
+type testRules []string
+
+var testRulesDecoder = DecoderFunc[testRules](func(b []byte) (testRules, error) {
+	var r testRules
+	err := json.Unmarshal(b, &r)
+	return r, err
+})
+
+// testServer is a fake GCOM API that serves the provided handler and counts the calls.
+type testServer struct {
+	*httptest.Server
+	calls atomic.Int32
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	srv := &testServer{}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		srv.calls.Add(1)
+		handler(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// rulesHandler returns a handler that serves the provided rules with the provided ETag, and replies with
+// 304 Not Modified if the request has a matching If-None-Match header.
+func rulesHandler(etag string, rules string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != testRulesPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(rules))
+	}
+}
+
+func newTestClient(t *testing.T, baseURL string) *Client[testRules] {
+	gcomClient := gcomclient.NewClient(http.DefaultTransport, setting.GCOMClientSettings{}, "", nil)
+	c := NewClient[testRules](testFeed, baseURL, gcomClient, testRulesDecoder, nil)
+	c.Backoff = backoff.Config{MaxRetries: 1}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	t.Run("fetch", func(t *testing.T) {
+		srv := newTestServer(t, rulesHandler(`"v1"`, `["a","b"]`))
+		c := newTestClient(t, srv.URL)
+
+		r, err := c.Fetch(context.Background(), CacheValidators{})
+		require.NoError(t, err)
+		require.Equal(t, testRules{"a", "b"}, r.Rules)
+		require.JSONEq(t, `["a","b"]`, string(r.Raw))
+		require.Equal(t, CacheValidators{ETag: `"v1"`}, r.Validators)
+		require.Equal(t, float64(1), testutil.ToFloat64(c.Metrics.HTTPResponses.WithLabelValues("200")))
+		require.Zero(t, testutil.ToFloat64(c.Metrics.FetchErrors))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		srv := newTestServer(t, rulesHandler(`"v1"`, `["a","b"]`))
+		c := newTestClient(t, srv.URL)
+
+		r, err := c.Fetch(context.Background(), CacheValidators{ETag: `"v1"`})
+		require.ErrorIs(t, err, ErrNotModified)
+		require.Equal(t, CacheValidators{ETag: `"v1"`}, r.Validators)
+		require.Zero(t, c.Breaker.ConsecutiveFailures())
+		require.Zero(t, testutil.ToFloat64(c.Metrics.FetchErrors))
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		srv := newTestServer(t, rulesHandler(`"v1"`, `{"not":"a list"}`))
+		c := newTestClient(t, srv.URL)
+
+		_, err := c.Fetch(context.Background(), CacheValidators{})
+		require.Error(t, err)
+		require.Equal(t, 1, c.Breaker.ConsecutiveFailures())
+		require.Equal(t, float64(1), testutil.ToFloat64(c.Metrics.FetchErrors))
+	})
+
+	t.Run("response too large", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(`["`))
+			_, _ = w.Write(bytes.Repeat([]byte("a"), maxRulesSize))
+			_, _ = w.Write([]byte(`"]`))
+		})
+		c := newTestClient(t, srv.URL)
+		c.Backoff = backoff.Config{MaxRetries: 2}
+
+		_, err := c.Fetch(context.Background(), CacheValidators{})
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+		require.Equal(t, int32(1), srv.calls.Load(), "oversized responses should not be retried")
+	})
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		var failed atomic.Bool
+		srv := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+			if failed.CompareAndSwap(false, true) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rulesHandler(`"v1"`, `["a"]`)(w, req)
+		})
+		c := newTestClient(t, srv.URL)
+		c.Backoff = backoff.Config{MaxRetries: 2}
+
+		r, err := c.Fetch(context.Background(), CacheValidators{})
+		require.NoError(t, err)
+		require.Equal(t, testRules{"a"}, r.Rules)
+		require.Equal(t, int32(2), srv.calls.Load())
+		require.Equal(t, float64(1), testutil.ToFloat64(c.Metrics.FetchRetries))
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		c := newTestClient(t, srv.URL)
+		c.Backoff = backoff.Config{MaxRetries: 2}
+
+		_, err := c.Fetch(context.Background(), CacheValidators{})
+		require.ErrorAs(t, err, &UnexpectedStatusCodeError{})
+		require.Equal(t, int32(1), srv.calls.Load())
+		require.Zero(t, testutil.ToFloat64(c.Metrics.FetchRetries))
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		srv := newTestServer(t, func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		c := newTestClient(t, srv.URL)
+
+		for i := 0; i < CircuitBreakerFailureThreshold; i++ {
+			_, err := c.Fetch(context.Background(), CacheValidators{})
+			require.Error(t, err)
+		}
+		require.Equal(t, CircuitBreakerOpen, c.Breaker.State())
+		require.Equal(t, float64(1), testutil.ToFloat64(c.Metrics.CircuitBreakerOpen))
+
+		_, err := c.Fetch(context.Background(), CacheValidators{})
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, int32(CircuitBreakerFailureThreshold), srv.calls.Load(), "gcom api should not be called")
+	})
+}
@@ -0,0 +1,77 @@
+package remoterules
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubSystem = "plugins"
+)
+
+// Metrics are the metrics shared by all the feeds. Each feed has its own metrics, prefixed by the feed name.
+type Metrics struct {
+	FetchDuration      prometheus.Histogram
+	FetchErrors        prometheus.Counter
+	HTTPResponses      *prometheus.CounterVec
+	FetchRetries       prometheus.Counter
+	CircuitBreakerOpen prometheus.Gauge
+	LastSuccess        prometheus.Gauge
+}
+
+// newMetrics returns the metrics of the provided feed.
+// Metrics are not registered if reg is nil.
+func newMetrics(reg prometheus.Registerer, feed FeedConfig) *Metrics {
+	m := &Metrics{
+		FetchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_fetch_duration_seconds",
+			Help:      "Duration of " + feed.description() + " fetches from GCOM",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		FetchErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_fetch_errors_total",
+			Help:      "Number of failed " + feed.description() + " fetches from GCOM",
+		}),
+		HTTPResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_fetch_responses_total",
+			Help:      "Number of " + feed.description() + " responses returned by GCOM, by HTTP status code",
+		}, []string{"status_code"}),
+		FetchRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_fetch_retries_total",
+			Help:      "Number of retried " + feed.description() + " fetches from GCOM",
+		}),
+		CircuitBreakerOpen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_circuit_breaker_open",
+			Help:      "Whether the circuit breaker for " + feed.description() + " fetches from GCOM is open (1) or closed (0)",
+		}),
+		LastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      feed.Name + "_last_success_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful " + feed.description() + " update",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.FetchDuration,
+			m.FetchErrors,
+			m.HTTPResponses,
+			m.FetchRetries,
+			m.CircuitBreakerOpen,
+			m.LastSuccess,
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,55 @@
+package remoterules
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// NextInterval returns the provided interval plus a random jitter between 0 and the provided jitter, so that
+// multiple Grafana instances do not call GCOM at the same time.
+func NextInterval(interval, jitter time.Duration) time.Duration {
+	if jitter > 0 {
+		// nolint:gosec
+		// We can ignore the gosec G404 warning since the jitter does not need to be cryptographically secure
+		interval += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return interval
+}
+
+// RunPeriodically calls fn every nextInterval(), until the provided context is done.
+// The first call happens when nextInterval() has passed since lastRun, or immediately if it already has.
+// It always returns the context error.
+func RunPeriodically(ctx context.Context, lastRun time.Time, nextInterval func() time.Duration, fn func()) error {
+	nextRunUntil := time.Until(lastRun.Add(nextInterval()))
+
+	ticker := time.NewTicker(nextInterval())
+	defer ticker.Stop()
+
+	var tick <-chan time.Time
+	if nextRunUntil <= 0 {
+		// Do first run immediately
+		firstTick := make(chan time.Time, 1)
+		tick = firstTick
+
+		firstTick <- time.Now()
+	} else {
+		// Do first run after a certain amount of time
+		ticker.Reset(nextRunUntil)
+		tick = ticker.C
+	}
+
+	// Keep running periodically
+	for {
+		select {
+		case <-tick:
+			fn()
+
+			// Restore default ticker if we run with a shorter interval the first time, with a new random jitter
+			ticker.Reset(nextInterval())
+			tick = ticker.C
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
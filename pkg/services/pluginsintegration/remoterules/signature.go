@@ -0,0 +1,68 @@
+package remoterules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// maxSignatureSize is the maximum size of a signature returned by GCOM.
+const maxSignatureSize = 64 * 1024
+
+// verifySignature fetches the detached signature of the rules from GCOM and checks it against the provided raw
+// rules payload, using c.PublicKey.
+func (c *Client[T]) verifySignature(ctx context.Context, payload []byte) error {
+	signature, err := c.fetchSignature(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch signature: %w", err)
+	}
+	return verifyDetachedSignature(c.PublicKey, payload, signature)
+}
+
+// fetchSignature fetches the armored detached signature of the rules from GCOM.
+func (c *Client[T]) fetchSignature(ctx context.Context) ([]byte, error) {
+	reqURL, err := url.JoinPath(c.BaseURL, c.feed.SignaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("url joinpath: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request with context: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			c.log.Error("Response body close error", "error", closeErr)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	b, err := readBody(resp.Body, maxSignatureSize)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return b, nil
+}
+
+// verifyDetachedSignature checks the armored detached signature of payload against the armored publicKey.
+func verifyDetachedSignature(publicKey string, payload []byte, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewBufferString(publicKey))
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(
+		keyring, bytes.NewReader(payload), bytes.NewReader(signature), &packet.Config{},
+	); err != nil {
+		return fmt.Errorf("failed to check signature: %w", err)
+	}
+	return nil
+}
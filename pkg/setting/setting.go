@@ -248,6 +248,9 @@ type Cfg struct {
 	PluginsCDNURLTemplate    string
 	PluginLogBackendRequests bool
 
+	AngularDetection AngularDetectionSettings
+	GCOMClient       GCOMClientSettings
+
 	// Panels
 	DisableSanitizeHtml bool
 
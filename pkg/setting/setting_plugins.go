@@ -1,14 +1,103 @@
 package setting
 
 import (
+	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/util"
 )
 
 // PluginSettings maps plugin id to map of key/value settings.
 type PluginSettings map[string]map[string]string
 
+// AngularEmptyPatternsPolicy determines what happens when GCOM returns an empty list of Angular detection patterns.
+type AngularEmptyPatternsPolicy string
+
+const (
+	// AngularEmptyPatternsPolicyKeep keeps the previously cached patterns.
+	AngularEmptyPatternsPolicyKeep AngularEmptyPatternsPolicy = "keep"
+	// AngularEmptyPatternsPolicyClear accepts the empty response and clears the cached patterns.
+	AngularEmptyPatternsPolicyClear AngularEmptyPatternsPolicy = "clear"
+)
+
+// AngularScanScope determines which plugin files are scanned when detecting Angular plugins.
+type AngularScanScope string
+
+const (
+	// AngularScanScopeModuleJS scans only the module.js file of the plugin.
+	AngularScanScopeModuleJS AngularScanScope = "module_js"
+	// AngularScanScopeAllJS scans all the JavaScript files of the plugin.
+	AngularScanScopeAllJS AngularScanScope = "all_js"
+)
+
+// AngularPatternsCacheBackend determines where the dynamic Angular detection patterns are cached.
+type AngularPatternsCacheBackend string
+
+const (
+	// AngularPatternsCacheBackendDatabase caches the patterns in the database.
+	AngularPatternsCacheBackendDatabase AngularPatternsCacheBackend = "database"
+	// AngularPatternsCacheBackendRemoteCache caches the patterns in the remote cache, shared by all the instances of
+	// a high availability setup. Only one instance fetches the patterns from GCOM at a time.
+	AngularPatternsCacheBackendRemoteCache AngularPatternsCacheBackend = "remote_cache"
+)
+
+// AngularDetectionSettings contains the settings used for detecting Angular plugins.
+type AngularDetectionSettings struct {
+	// BlockCriticalOnInstall refuses plugin installations matching critical-severity Angular detection patterns.
+	BlockCriticalOnInstall bool
+	// EmptyPatternsPolicy determines what happens when GCOM returns an empty list of patterns.
+	EmptyPatternsPolicy AngularEmptyPatternsPolicy
+	// ScanScope determines which plugin files are scanned.
+	ScanScope AngularScanScope
+	// MaxFileSize is the maximum number of bytes scanned for each file. 0 means no limit.
+	MaxFileSize int64
+	// MaxPluginBytes is the maximum number of bytes scanned for each plugin, across all files. 0 means no limit.
+	MaxPluginBytes int64
+	// RequirePatternsSignature requires a valid detached signature for the patterns fetched from GCOM.
+	RequirePatternsSignature bool
+	// RefreshInterval is the interval between dynamic patterns refreshes.
+	RefreshInterval time.Duration
+	// RefreshJitter is the maximum random delay added to each refresh interval.
+	RefreshJitter time.Duration
+	// CacheBackend determines where the dynamic patterns are cached.
+	CacheBackend AngularPatternsCacheBackend
+	// PatternsMaxAge is the maximum age of the cached dynamic patterns, after which the static patterns are merged
+	// in. 0 means no limit.
+	PatternsMaxAge time.Duration
+	// Exclusions contains the IDs of the plugins that are never marked as Angular, regardless of the patterns
+	// they match.
+	Exclusions []string
+}
+
+// GCOMClientSettings contains the settings of the HTTP client used to call the GCOM API.
+type GCOMClientSettings struct {
+	// CACertPath is the path of a PEM-encoded CA bundle used to verify the GCOM certificate, in addition to the
+	// system CAs.
+	CACertPath string
+	// ClientCertPath is the path of the PEM-encoded client certificate used for mutual TLS.
+	ClientCertPath string
+	// ClientKeyPath is the path of the PEM-encoded private key of the client certificate used for mutual TLS.
+	ClientKeyPath string
+	// ProxyURL is the URL of the proxy used to call GCOM. If empty, the proxy is read from the environment.
+	ProxyURL string
+	// ProxyUsername is the username used to authenticate to the proxy.
+	ProxyUsername string
+	// ProxyPassword is the password used to authenticate to the proxy.
+	ProxyPassword string
+	// RequestsPerMinute is the maximum number of requests per minute sent to GCOM by this instance.
+	// 0 means no limit.
+	RequestsPerMinute int
+	// RequestBurst is the maximum number of requests that can be sent to GCOM at once, above RequestsPerMinute.
+	RequestBurst int
+}
+
+// minAngularPatternsRefreshInterval is the minimum allowed value for angular_patterns_refresh_interval.
+const minAngularPatternsRefreshInterval = time.Minute * 10
+
 func extractPluginSettings(sections []*ini.Section) PluginSettings {
 	psMap := PluginSettings{}
 	for _, section := range sections {
@@ -54,5 +143,81 @@ func (cfg *Cfg) readPluginSettings(iniFile *ini.File) error {
 	cfg.PluginsCDNURLTemplate = strings.TrimRight(pluginsSection.Key("cdn_base_url").MustString(""), "/")
 	cfg.PluginLogBackendRequests = pluginsSection.Key("log_backend_requests").MustBool(false)
 
+	// Angular detection settings
+	cfg.AngularDetection = AngularDetectionSettings{
+		BlockCriticalOnInstall:   pluginsSection.Key("angular_detection_block_critical_on_install").MustBool(false),
+		EmptyPatternsPolicy:      AngularEmptyPatternsPolicy(pluginsSection.Key("angular_patterns_empty_response_policy").MustString(string(AngularEmptyPatternsPolicyKeep))),
+		ScanScope:                AngularScanScope(pluginsSection.Key("angular_detection_scan_scope").MustString(string(AngularScanScopeModuleJS))),
+		MaxFileSize:              pluginsSection.Key("angular_detection_max_file_size").MustInt64(0),
+		MaxPluginBytes:           pluginsSection.Key("angular_detection_max_plugin_bytes").MustInt64(0),
+		RequirePatternsSignature: pluginsSection.Key("angular_patterns_signature_required").MustBool(false),
+		RefreshInterval:          pluginsSection.Key("angular_patterns_refresh_interval").MustDuration(time.Hour),
+		RefreshJitter:            pluginsSection.Key("angular_patterns_refresh_jitter").MustDuration(time.Minute * 5),
+		CacheBackend:             AngularPatternsCacheBackend(pluginsSection.Key("angular_patterns_cache_backend").MustString(string(AngularPatternsCacheBackendDatabase))),
+		PatternsMaxAge:           pluginsSection.Key("angular_patterns_max_age").MustDuration(0),
+		Exclusions:               util.SplitString(pluginsSection.Key("angular_detection_exclusions").MustString("")),
+	}
+	switch cfg.AngularDetection.EmptyPatternsPolicy {
+	case AngularEmptyPatternsPolicyKeep, AngularEmptyPatternsPolicyClear:
+	default:
+		return fmt.Errorf("invalid angular_patterns_empty_response_policy %q, must be one of: %s, %s",
+			cfg.AngularDetection.EmptyPatternsPolicy, AngularEmptyPatternsPolicyKeep, AngularEmptyPatternsPolicyClear)
+	}
+	switch cfg.AngularDetection.ScanScope {
+	case AngularScanScopeModuleJS, AngularScanScopeAllJS:
+	default:
+		return fmt.Errorf("invalid angular_detection_scan_scope %q, must be one of: %s, %s",
+			cfg.AngularDetection.ScanScope, AngularScanScopeModuleJS, AngularScanScopeAllJS)
+	}
+	if cfg.AngularDetection.MaxFileSize < 0 || cfg.AngularDetection.MaxPluginBytes < 0 {
+		return fmt.Errorf("angular_detection_max_file_size and angular_detection_max_plugin_bytes must not be negative")
+	}
+	if cfg.AngularDetection.RefreshInterval < minAngularPatternsRefreshInterval {
+		return fmt.Errorf("angular_patterns_refresh_interval must be at least %s", minAngularPatternsRefreshInterval)
+	}
+	if cfg.AngularDetection.RefreshJitter < 0 {
+		return fmt.Errorf("angular_patterns_refresh_jitter must not be negative")
+	}
+	switch cfg.AngularDetection.CacheBackend {
+	case AngularPatternsCacheBackendDatabase, AngularPatternsCacheBackendRemoteCache:
+	default:
+		return fmt.Errorf("invalid angular_patterns_cache_backend %q, must be one of: %s, %s",
+			cfg.AngularDetection.CacheBackend, AngularPatternsCacheBackendDatabase, AngularPatternsCacheBackendRemoteCache)
+	}
+	if maxAge := cfg.AngularDetection.PatternsMaxAge; maxAge != 0 && maxAge <= cfg.AngularDetection.RefreshInterval {
+		return fmt.Errorf("angular_patterns_max_age must be 0 or greater than angular_patterns_refresh_interval")
+	}
+
+	// GCOM client settings
+	if err := cfg.readGCOMClientSettings(iniFile.Section("plugins.gcom")); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cfg *Cfg) readGCOMClientSettings(section *ini.Section) error {
+	cfg.GCOMClient = GCOMClientSettings{
+		CACertPath:     section.Key("ca_cert_path").MustString(""),
+		ClientCertPath: section.Key("client_cert_path").MustString(""),
+		ClientKeyPath:  section.Key("client_key_path").MustString(""),
+		ProxyURL:       section.Key("proxy_url").MustString(""),
+		ProxyUsername:  section.Key("proxy_username").MustString(""),
+		ProxyPassword:  section.Key("proxy_password").MustString(""),
+
+		RequestsPerMinute: section.Key("requests_per_minute").MustInt(60),
+		RequestBurst:      section.Key("request_burst").MustInt(10),
+	}
+	if (cfg.GCOMClient.ClientCertPath == "") != (cfg.GCOMClient.ClientKeyPath == "") {
+		return fmt.Errorf("[plugins.gcom] client_cert_path and client_key_path must be set together")
+	}
+	if cfg.GCOMClient.ProxyURL != "" {
+		if _, err := url.Parse(cfg.GCOMClient.ProxyURL); err != nil {
+			return fmt.Errorf("invalid [plugins.gcom] proxy_url: %w", err)
+		}
+	}
+	if cfg.GCOMClient.RequestsPerMinute < 0 || cfg.GCOMClient.RequestBurst < 0 {
+		return fmt.Errorf("[plugins.gcom] requests_per_minute and request_burst must not be negative")
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package setting
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -41,3 +42,141 @@ func TestPluginSettings(t *testing.T) {
 	require.Equal(t, ps["plugin2"]["key3"], "value3")
 	require.Equal(t, ps["plugin2"]["key4"], "value4")
 }
+
+func TestAngularDetectionSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readPluginSettings(cfg.Raw))
+		require.Equal(t, AngularEmptyPatternsPolicyKeep, cfg.AngularDetection.EmptyPatternsPolicy)
+		require.Equal(t, AngularScanScopeModuleJS, cfg.AngularDetection.ScanScope)
+		require.Equal(t, time.Hour, cfg.AngularDetection.RefreshInterval)
+		require.Equal(t, time.Minute*5, cfg.AngularDetection.RefreshJitter)
+		require.Equal(t, AngularPatternsCacheBackendDatabase, cfg.AngularDetection.CacheBackend)
+		require.Zero(t, cfg.AngularDetection.PatternsMaxAge)
+		require.Empty(t, cfg.AngularDetection.Exclusions)
+	})
+
+	t.Run("exclusions", func(t *testing.T) {
+		cfg := NewCfg()
+		_, err := cfg.Raw.Section("plugins").NewKey("angular_detection_exclusions", "plugin-id-a, plugin-id-b")
+		require.NoError(t, err)
+		require.NoError(t, cfg.readPluginSettings(cfg.Raw))
+		require.Equal(t, []string{"plugin-id-a", "plugin-id-b"}, cfg.AngularDetection.Exclusions)
+	})
+
+	t.Run("empty response policy", func(t *testing.T) {
+		for _, tc := range []struct {
+			value     string
+			expPolicy AngularEmptyPatternsPolicy
+			valid     bool
+		}{
+			{value: "", expPolicy: AngularEmptyPatternsPolicyKeep, valid: true},
+			{value: "keep", expPolicy: AngularEmptyPatternsPolicyKeep, valid: true},
+			{value: "clear", expPolicy: AngularEmptyPatternsPolicyClear, valid: true},
+			{value: "invalid", valid: false},
+		} {
+			t.Run(tc.value, func(t *testing.T) {
+				cfg := NewCfg()
+				if tc.value != "" {
+					_, err := cfg.Raw.Section("plugins").NewKey("angular_patterns_empty_response_policy", tc.value)
+					require.NoError(t, err)
+				}
+				err := cfg.readPluginSettings(cfg.Raw)
+				if !tc.valid {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, tc.expPolicy, cfg.AngularDetection.EmptyPatternsPolicy)
+			})
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		key   string
+		value string
+		valid bool
+	}{
+		{name: "valid refresh interval", key: "angular_patterns_refresh_interval", value: "30m", valid: true},
+		{name: "refresh interval too short", key: "angular_patterns_refresh_interval", value: "1m", valid: false},
+		{name: "negative refresh jitter", key: "angular_patterns_refresh_jitter", value: "-1m", valid: false},
+		{name: "invalid empty patterns policy", key: "angular_patterns_empty_response_policy", value: "invalid", valid: false},
+		{name: "invalid scan scope", key: "angular_detection_scan_scope", value: "invalid", valid: false},
+		{name: "remote cache backend", key: "angular_patterns_cache_backend", value: "remote_cache", valid: true},
+		{name: "invalid cache backend", key: "angular_patterns_cache_backend", value: "invalid", valid: false},
+		{name: "valid max age", key: "angular_patterns_max_age", value: "168h", valid: true},
+		{name: "max age shorter than refresh interval", key: "angular_patterns_max_age", value: "30m", valid: false},
+		{name: "negative max age", key: "angular_patterns_max_age", value: "-1h", valid: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewCfg()
+			_, err := cfg.Raw.Section("plugins").NewKey(tc.key, tc.value)
+			require.NoError(t, err)
+
+			err = cfg.readPluginSettings(cfg.Raw)
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGCOMClientSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := NewCfg()
+		require.NoError(t, cfg.readPluginSettings(cfg.Raw))
+		require.Equal(t, GCOMClientSettings{RequestsPerMinute: 60, RequestBurst: 10}, cfg.GCOMClient)
+	})
+
+	t.Run("reads plugins.gcom section", func(t *testing.T) {
+		cfg := NewCfg()
+		sec := cfg.Raw.Section("plugins.gcom")
+		for k, v := range map[string]string{
+			"ca_cert_path":        "/etc/ssl/ca.pem",
+			"client_cert_path":    "/etc/ssl/client.pem",
+			"client_key_path":     "/etc/ssl/client.key",
+			"proxy_url":           "http://proxy:3128",
+			"proxy_username":      "user",
+			"proxy_password":      "password",
+			"requests_per_minute": "120",
+			"request_burst":       "5",
+		} {
+			_, err := sec.NewKey(k, v)
+			require.NoError(t, err)
+		}
+		require.NoError(t, cfg.readPluginSettings(cfg.Raw))
+		require.Equal(t, GCOMClientSettings{
+			CACertPath:     "/etc/ssl/ca.pem",
+			ClientCertPath: "/etc/ssl/client.pem",
+			ClientKeyPath:  "/etc/ssl/client.key",
+			ProxyURL:       "http://proxy:3128",
+			ProxyUsername:  "user",
+			ProxyPassword:  "password",
+
+			RequestsPerMinute: 120,
+			RequestBurst:      5,
+		}, cfg.GCOMClient)
+	})
+
+	for _, tc := range []struct {
+		name string
+		key  string
+		val  string
+	}{
+		{name: "client cert without key", key: "client_cert_path", val: "/etc/ssl/client.pem"},
+		{name: "client key without cert", key: "client_key_path", val: "/etc/ssl/client.key"},
+		{name: "invalid proxy url", key: "proxy_url", val: "http://proxy:port"},
+		{name: "negative requests per minute", key: "requests_per_minute", val: "-1"},
+		{name: "negative request burst", key: "request_burst", val: "-1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewCfg()
+			_, err := cfg.Raw.Section("plugins.gcom").NewKey(tc.key, tc.val)
+			require.NoError(t, err)
+			require.Error(t, cfg.readPluginSettings(cfg.Raw))
+		})
+	}
+}